@@ -0,0 +1,15 @@
+/** The part of Node's EventEmitter Go instances have on hosts without it. */
+declare class Emitter {
+  on(name: string | symbol, listener: (...args: any[]) => void): this;
+  addListener(name: string | symbol, listener: (...args: any[]) => void): this;
+  once(name: string | symbol, listener: (...args: any[]) => void): this;
+  off(name: string | symbol, listener: (...args: any[]) => void): this;
+  removeListener(name: string | symbol, listener: (...args: any[]) => void): this;
+  removeAllListeners(name?: string | symbol): this;
+  listeners(name: string | symbol): Function[];
+  listenerCount(name: string | symbol): number;
+  /** Throws an 'error' nobody listens to. */
+  emit(name: string | symbol, ...args: any[]): boolean;
+}
+
+export = Emitter;
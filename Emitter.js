@@ -0,0 +1,71 @@
+// Emitter is the part of Node's EventEmitter Go instances have on hosts
+// without it.
+class Emitter {
+  constructor() {
+    this._listeners = new Map();
+  }
+
+  on(name, listener) {
+    this._listeners.set(name, this.listeners(name).concat(listener));
+    return this;
+  }
+
+  addListener(name, listener) {
+    return this.on(name, listener);
+  }
+
+  once(name, listener) {
+    const once = (...args) => {
+      this.off(name, once);
+      listener.apply(this, args);
+    };
+    once.listener = listener;
+    return this.on(name, once);
+  }
+
+  off(name, listener) {
+    const listeners = this.listeners(name);
+    const i = listeners.findIndex(l => l === listener || l.listener === listener);
+    if (i !== -1) {
+      listeners.splice(i, 1);
+      this._listeners.set(name, listeners);
+    }
+    return this;
+  }
+
+  removeListener(name, listener) {
+    return this.off(name, listener);
+  }
+
+  removeAllListeners(name) {
+    if (name === undefined) {
+      this._listeners.clear();
+    } else {
+      this._listeners.delete(name);
+    }
+    return this;
+  }
+
+  listeners(name) {
+    return (this._listeners.get(name) || []).slice();
+  }
+
+  listenerCount(name) {
+    return this.listeners(name).length;
+  }
+
+  emit(name, ...args) {
+    const listeners = this.listeners(name);
+    if (name === 'error' && !listeners.length) {
+      throw args[0] instanceof Error ? args[0] : new Error(`unhandled error event: ${args[0]}`);
+    }
+    listeners.forEach(listener => listener.apply(this, args));
+    return listeners.length > 0;
+  }
+}
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = Emitter;
+} else {
+  globalThis.GoEmitter = Emitter;
+}
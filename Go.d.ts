@@ -0,0 +1,227 @@
+import { EventEmitter } from 'events';
+import { Duplex } from 'stream';
+import Tracer = require('./Tracer');
+import Symbols = require('./Symbols');
+import Profiler = require('./Profiler');
+import Clock = require('./VirtualClock');
+import Imports = require('./Imports');
+
+/**
+ * Runs a Go program built with GOOS=js GOARCH=wasm. Exports describes the
+ * functions the program registers on exports, see gojsbuild.
+ *
+ * Emits 'exit' with the exit code and the events the program emits with
+ * the events package, with their JSON payload decoded.
+ */
+declare class Go<Exports extends object = Record<string, Go.ExportedFunction>> extends EventEmitter {
+  /** Construction doesn't wait for source to load, run() and waitLoaded() do. */
+  constructor(source: Go.Source | PromiseLike<Go.Source>, options?: Go.Options);
+
+  /** Environment variables of the program. */
+  env: Record<string, string>;
+  /** Called with the exit code when the program exits. */
+  exit: (code: number) => void;
+  /** Functions registered by the program through the interop package. */
+  readonly exports: Exports;
+  /** The object js.Global() returns in Go. */
+  readonly global: Record<string, unknown>;
+  readonly globals: Record<string, unknown>;
+  readonly source: Go.Source | PromiseLike<Go.Source>;
+  readonly module: WebAssembly.Module | undefined;
+  /** The platform this instance runs on. */
+  readonly platform: Go.Platform;
+  readonly instance: WebAssembly.Instance | undefined;
+  readonly tracer?: Tracer;
+  readonly profiler?: Profiler;
+  /** Go symbols of the loaded module, which trap stacks are rewritten with. */
+  readonly symbols: Symbols;
+  /** The virtual clock in deterministic mode. */
+  readonly clock?: Clock;
+  readonly capture: boolean;
+  readonly argv0: string;
+  readonly maxMemory?: number;
+  readonly fuel?: number;
+  readonly exited: boolean;
+  readonly running: boolean;
+  readonly loaded: boolean;
+  readonly stats: Go.Stats;
+  /** Fuel used by the last run of a module instrumented by cmd/wasmfuel. */
+  readonly fuelUsed: number | undefined;
+  /** Number of JS values Go currently holds references to. */
+  readonly liveValues: number;
+
+  load(): Promise<void>;
+  /** Instantiates the module again so it can be run again. */
+  reset(): void;
+  waitLoaded(): Promise<void>;
+  /** Resolves once main first returns control to JS, when exports are available. */
+  waitReady(): Promise<void>;
+  /** Runs the program with args, non-strings are passed as JSON. */
+  run(...args: unknown[]): Promise<Go.RunResult>;
+  /**
+   * Runs the program with args, writes to the stream are its stdin and its
+   * stdout is read from it. Errors with exitCode if that isn't 0. Node only.
+   */
+  createStream(...args: unknown[]): Duplex;
+
+  static GoPanicError: typeof Go.GoPanicError;
+  static GoMemoryError: typeof Go.GoMemoryError;
+  static GoFuelError: typeof Go.GoFuelError;
+  static GoImportError: typeof Imports.GoImportError;
+  /** The gojs imports Go.js implements, like 'syscall/js.valueGet'. */
+  static hostFunctions: readonly string[];
+  /** The Go release that introduced each import Go has had, like 'go1.13' for 'syscall/js.copyBytesToJS'. */
+  static importReleases: Readonly<Record<string, string>>;
+  static VirtualClock: typeof Clock;
+  /** The globals instances start from, frozen. */
+  static readonly defaultGlobals: Readonly<Record<string, unknown>>;
+  /** The platform new instances run on by default, Node's or the web one. */
+  static platform: Go.Platform;
+  /** Sets the default platform and the defaultGlobals that come with it; existing instances keep theirs. */
+  static usePlatform(platform: Go.Platform): void;
+  static Tracer: typeof Tracer;
+  static Symbols: typeof Symbols;
+  static Profiler: typeof Profiler;
+  /** Compiles any source the constructor accepts. */
+  static compile(source: Go.Source | PromiseLike<Go.Source>, platform?: Go.Platform): Promise<WebAssembly.Module>;
+  /** The smallest allow list a Go program printing to stdout runs with. */
+  static runtimeGlobals: string[];
+}
+
+declare namespace Go {
+  interface Options {
+    /** Log every call into Go.js with decoded arguments and results. */
+    debug?: boolean;
+    /** Record calls into Go.js; a file name also writes a Chrome trace on exit. */
+    trace?: boolean | string;
+    /** Values added to what js.Global() returns in Go. */
+    globals?: Record<string, unknown>;
+    /** Use only globals instead of adding them to Go.defaultGlobals. */
+    replaceGlobals?: boolean;
+    /** Names or dotted member paths of globals Go may see, e.g. ['console', 'fs.write']. Not a sandbox: Object's constructor still reaches Function. */
+    allow?: string[];
+    /** Collect stdout and stderr and return them from run(). */
+    capture?: boolean;
+    /** Called for each read of stdin; resolves to the next chunk, or null at its end. */
+    stdin?: () => Uint8Array | string | null | PromiseLike<Uint8Array | string | null>;
+    /** Called with what Go writes to stdout; a returned promise holds back the write until it settles. */
+    stdout?: (chunk: Uint8Array) => unknown;
+    /** Like stdout, for stderr. */
+    stderr?: (chunk: Uint8Array) => unknown;
+    /** The program name Go sees as os.Args[0], 'main.wasm' by default. */
+    argv0?: string;
+    /** Limit of the linear memory in bytes; the source mustn't be a compiled module. */
+    maxMemory?: number;
+    /** Compute budget of a run; the module must be instrumented by cmd/wasmfuel. */
+    fuel?: number;
+    /** Run on a virtual clock with seeded random data, so runs are repeatable. */
+    deterministic?: boolean | DeterministicOptions;
+    /** Sample the program while it runs, Node only; a file name writes a pprof profile on exit. */
+    cpuProfile?: boolean | string | Profiler.Options;
+    /** The host to run on, Go.platform by default. */
+    platform?: Platform;
+  }
+
+  interface DeterministicOptions {
+    /** Seed of the random data Go reads, 0 by default. */
+    seed?: number;
+    /** Wall clock at the start in ms since the epoch, 2000-01-01 by default. */
+    time?: number;
+    /** Jump to the next timer once JS is idle, true by default. */
+    autoAdvance?: boolean;
+  }
+
+  /**
+   * Where to load wasm from: a path (a URL in browsers), a URL, the bytes,
+   * a compiled module, a fetch Response or a web or Node readable stream.
+   */
+  type Source = string | URL | ArrayBuffer | ArrayBufferView | WebAssembly.Module | Response | ReadableStream<Uint8Array> | AsyncIterable<Uint8Array>;
+
+  /** What Go.js needs from its host, see platform/node.js. */
+  interface Platform {
+    name: string;
+    /** Monotonic clock in milliseconds. */
+    now(): number;
+    randomFill(buf: Uint8Array): void;
+    /** Writes to stdout (1) or stderr (2). */
+    writeSync(fd: number, buf: Uint8Array): void;
+    loadFile(file: string | URL): Promise<BufferSource | Response>;
+    writeFile(file: string, data: string | Uint8Array): void;
+    /** fs, process and path for the Go runtime. */
+    globals: Record<string, unknown>;
+    /** Node's Duplex, which createStream needs. */
+    Duplex?: typeof Duplex;
+    /** Starts V8's CPU profiler, which Profiler needs; resolves to a function stopping it. */
+    startProfiler?(interval: number): Promise<() => Promise<object>>;
+  }
+
+  interface Stats {
+    /** Milliseconds spent running wasm. */
+    wasmTime: number;
+    /** Milliseconds spent in Go.js imports. */
+    hostTime: number;
+    /** Largest size of linear memory in bytes. */
+    peakMemory: number;
+    /** JS values handed to Go. */
+    values: number;
+    /** Bytes written by the runtime through wasmWrite. */
+    writeBytes: number;
+    /** Fuel used, for modules instrumented by cmd/wasmfuel. */
+    fuel?: number;
+  }
+
+  interface RunResult {
+    code: number;
+    stats: Stats;
+    /** Set when the capture option is. */
+    stdout?: string;
+    stderr?: string;
+  }
+
+  /** A function exported by Go, as called from JS. */
+  type ExportedFunction = (...args: any[]) => any;
+
+  /** Thrown into JS when a Go function exported through interop panics. */
+  class GoPanicError extends Error {
+    constructor(message: string, goStack?: string);
+    name: 'GoPanicError';
+    /** Stack of the panicking goroutine. */
+    goStack: string;
+  }
+
+  /** What run() rejects with when the Go runtime runs out of memory. */
+  class GoMemoryError extends Error {
+    constructor(heapSize: number, maxMemory?: number, requested?: number);
+    name: 'GoMemoryError';
+    /** Size of the linear memory in bytes when it ran out. */
+    heapSize: number;
+    /** The maxMemory option, if set. */
+    maxMemory?: number;
+    /** Size of the allocation that failed, if the runtime said. */
+    requested?: number;
+  }
+
+  /** What run() and waitLoaded() reject with when the module imports functions Go.js doesn't implement, see Imports.js. */
+  type GoImportError = Imports.GoImportError;
+
+  /** The clock of a deterministic instance, see VirtualClock.js. */
+  type VirtualClock = Clock;
+
+  /** What run() rejects with, and calls of exported Go functions throw, when the program runs out of fuel. */
+  class GoFuelError extends Error {
+    constructor(fuel: number, used: number);
+    name: 'GoFuelError';
+    /** The fuel option. */
+    fuel: number;
+    /** Fuel used when the program was stopped, a little past fuel. */
+    used: number;
+  }
+
+  /** What run() rejects with, and calls of exported Go functions throw, when the module traps, e.g. accessing memory out of bounds. */
+  interface Trap extends WebAssembly.RuntimeError {
+    /** The Go frames it happened in, like a Go traceback. */
+    goStack: string;
+  }
+}
+
+export = Go;
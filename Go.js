@@ -1,21 +1,305 @@
-const fs = require('fs');
-const { TextDecoder, TextEncoder } = require('util');
-const crypto = require('crypto');
+// Go.js is CommonJS under Node. Browsers and Deno load it as a plain script
+// module through esm/web.mjs, so everything host specific lives in the
+// platform an instance runs on, the one set with Go.usePlatform unless
+// given, see platform/node.js.
 
 const encoder = new TextEncoder('utf-8');
 const decoder = new TextDecoder('utf-8');
 
-class Go {
-  constructor(filepath, { debug } = {}) {
-    this.source = fs.readFileSync(filepath);
+// The linker guarantees global data starts from at least wasmMinDataAddr,
+// so args and env have to fit between 4096 and that address.
+const wasmMinDataAddr = 4096 + 8192;
+
+// GoPanicError is thrown into JS when a Go function exported through the
+// interop package panics. goStack holds the stack of the panicking goroutine.
+class GoPanicError extends Error {
+  constructor(message, goStack = '') {
+    super(message);
+    this.name = 'GoPanicError';
+    this.goStack = goStack;
+    if (goStack) {
+      this.stack = `${this.name}: ${message}\n\n${goStack}`;
+    }
+  }
+}
+
+// goTrap gives err, a trap of the wasm module, the Go stack it happened on
+// as goStack, and as its stack like GoPanicError, as far as symbols can
+// tell from the frames the engine captured and Go's stack in memory at sp.
+const goTrap = (err, symbols, memory, sp) => {
+  try {
+    err.goStack = symbols.traceback(err.stack, memory, sp);
+  } catch (_) {
+    // leave the engine's stack to a module Symbols can't read
+    err.goStack = '';
+  }
+  if (err.goStack) {
+    err.stack = `${err.name}: ${err.message}\n\n${err.goStack}`;
+  }
+  return err;
+};
+
+// toError makes sure anything thrown by JS code called from Go has a name,
+// message and stack for the interop package to read.
+const toError = (err) => {
+  if (err instanceof Error) {
+    return err;
+  }
+  const wrapped = new Error(String(err));
+  wrapped.name = 'NonErrorException';
+  wrapped.value = err;
+  return wrapped;
+};
+
+// Go.js's parts in files of their own put themselves on globalThis outside
+// CommonJS, where esm/web.mjs loads them first
+const commonJS = typeof module === 'object' && module.exports;
+const EventEmitter = commonJS ? require('events') : globalThis.GoEmitter;
+const VirtualClock = commonJS ? require('./VirtualClock') : globalThis.GoVirtualClock;
+const { wasmPageSize, limitMemory } = commonJS ? require('./WasmBinary') : globalThis.GoWasmBinary;
+const { hostFunctions, importReleases, checkImports, GoImportError } = commonJS ? require('./Imports') : globalThis.GoImports;
+const { seededRandom } = VirtualClock;
+
+// the most linear memory a 32-bit wasm memory can grow to
+const maxWasmMemory = 65536 * wasmPageSize;
+// Go's runtime grows the heap by arenas of this many bytes under js/wasm
+const heapArenaBytes = 4 << 20;
+// events the instance emits itself or EventEmitter treats specially, which
+// Go programs can't emit through GoEvents
+const reservedEvents = ['exit', 'error', 'newListener', 'removeListener'];
+
+// concat joins the chunks of captured output into a string
+const concat = (chunks) => {
+  const dec = new TextDecoder('utf-8');
+  return chunks.map(chunk => dec.decode(chunk, { stream: true })).join('') + dec.decode();
+};
+
+// the bytes modules were compiled from, for Symbols to read lines from
+const moduleBytes = new WeakMap();
+
+// compile makes a WebAssembly.Module of source: a path or URL, bytes, a
+// WebAssembly.Module, a fetch Response, or a stream, or a promise of one.
+// Paths and other URLs are loaded by the platform, http(s) URLs fetched.
+// Responses with an application/wasm content type and web streams compile
+// while they download, unless patch is given: it is called with the bytes
+// and returns those to compile, which a compiled module can't be.
+const compile = async (source, platform, patch) => {
+  if (typeof source === 'string' || source instanceof URL) {
+    source = /^https?:/.test(String(source)) && typeof fetch === 'function' ? fetch(source) : platform.loadFile(source);
+  }
+  source = await source;
+  const compileBytes = (bytes) => {
+    // the engine takes typed arrays but not every view, e.g. a DataView
+    bytes = ArrayBuffer.isView(bytes) ? new Uint8Array(bytes.buffer, bytes.byteOffset, bytes.byteLength) : new Uint8Array(bytes);
+    if (patch) {
+      bytes = patch(bytes);
+    }
+    return WebAssembly.compile(bytes).then((module) => {
+      moduleBytes.set(module, bytes);
+      return module;
+    });
+  };
+  if (source instanceof WebAssembly.Module) {
+    if (patch) {
+      throw new TypeError('a compiled WebAssembly.Module can\'t be changed, pass its bytes');
+    }
+    return source;
+  }
+  if (source instanceof ArrayBuffer || ArrayBuffer.isView(source)) {
+    return compileBytes(source);
+  }
+  if (typeof ReadableStream === 'function' && source instanceof ReadableStream) {
+    source = new Response(source, { headers: { 'Content-Type': 'application/wasm' } });
+  }
+  if (typeof Response === 'function' && source instanceof Response) {
+    if (!source.ok) {
+      throw new Error(`fetching ${source.url}: ${source.status} ${source.statusText}`);
+    }
+    if (!patch && WebAssembly.compileStreaming && /^application\/wasm\b/.test(source.headers.get('Content-Type'))) {
+      return WebAssembly.compileStreaming(source);
+    }
+    return compileBytes(await source.arrayBuffer());
+  }
+  if (source && typeof source[Symbol.asyncIterator] === 'function') {
+    // e.g. a Node readable stream
+    const chunks = [];
+    let length = 0;
+    for await (const chunk of source) {
+      if (typeof chunk === 'string') {
+        throw new TypeError('wasm stream must not have an encoding set');
+      }
+      chunks.push(chunk);
+      length += chunk.length;
+    }
+    const bytes = new Uint8Array(length);
+    chunks.reduce((offset, chunk) => {
+      bytes.set(chunk, offset);
+      return offset + chunk.length;
+    }, 0);
+    return compileBytes(bytes);
+  }
+  throw new TypeError(`cannot load wasm from ${Object.prototype.toString.call(source)}`);
+};
+
+// GoFuelError is what run() rejects with when the program runs out of the
+// fuel it was given with the fuel option.
+class GoFuelError extends Error {
+  constructor(fuel, used) {
+    super(`Go program ran out of fuel, using ${used} of ${fuel}`);
+    this.name = 'GoFuelError';
+    this.fuel = fuel;
+    this.used = used;
+  }
+}
+
+// GoMemoryError is what run() rejects with when the Go runtime runs out of
+// memory, which it does when memory.grow fails: past the maxMemory option
+// or at the engine's limit. Go.js tells by the program exiting with an
+// error after the runtime said on stderr that it couldn't allocate, with
+// too little memory left below that limit for what it wanted. heapSize is
+// the size of the linear memory at that point in bytes, requested what
+// the runtime failed to allocate if it said.
+class GoMemoryError extends Error {
+  constructor(heapSize, maxMemory, requested) {
+    const want = requested ? `allocating ${requested} bytes ` : '';
+    const limit = maxMemory ? ` of maxMemory ${maxMemory}` : '';
+    super(`Go program ran out of memory ${want}with a heap of ${heapSize} bytes${limit}`);
+    this.name = 'GoMemoryError';
+    this.heapSize = heapSize;
+    this.maxMemory = maxMemory;
+    this.requested = requested;
+  }
+}
+
+// restrict builds an object holding only the allowed paths of source.
+// 'fs' exposes fs as is, 'fs.write' exposes an object whose only member is
+// fs.write bound to fs. Paths source doesn't have are left out.
+const restrict = (source, allow) => {
+  const out = {};
+  const facades = new WeakSet();
+  allow.forEach((name) => {
+    const keys = name.split('.');
+    const last = keys.pop();
+    const parent = keys.reduce((obj, key) => (obj == null ? undefined : obj[key]), source);
+    const value = parent == null ? undefined : parent[last];
+    if (value === undefined) {
+      return;
+    }
+    let dst = out;
+    for (const key of keys) {
+      if (!Object.prototype.hasOwnProperty.call(dst, key)) {
+        dst[key] = {};
+        facades.add(dst[key]);
+      }
+      // a parent exposed as a whole already contains the path
+      if (!facades.has(dst[key])) {
+        return;
+      }
+      dst = dst[key];
+    }
+    dst[last] = typeof value === 'function' && keys.length ? value.bind(parent) : value;
+  });
+  return out;
+};
+
+// Go is an EventEmitter. Besides 'exit', emitted with the exit code, it
+// emits the events the program emits through the events package, with the
+// payload decoded from JSON.
+class Go extends EventEmitter {
+  // debug logs every call into Go.js with decoded arguments and results.
+  // trace records the calls without logging; pass a file name to also
+  // write them as a Chrome trace when the program exits.
+  //
+  // globals are added to what js.Global() returns in Go, replacing the
+  // defaults entirely if replaceGlobals is set. allow limits that to the
+  // listed names or dotted member paths, e.g. ['console', 'fs.write']. It
+  // is no sandbox: Object's constructor is Function, which reaches all of
+  // the host's globals.
+  //
+  // capture collects what the program writes to stdout and stderr and
+  // returns it from run() instead of writing it to the process.
+  //
+  // stdin, stdout and stderr replace the program's standard streams.
+  // stdin is called whenever Go reads and returns (a promise of) the next
+  // chunk of input, or null at its end. stdout and stderr are called with
+  // each chunk Go writes; returning a promise makes Go wait for it before
+  // writing on, e.g. until a stream drained. Output the runtime writes
+  // itself, like a panic, doesn't wait.
+  //
+  // argv0 is the program name Go sees as os.Args[0].
+  //
+  // maxMemory limits the program's linear memory to that many bytes, so it
+  // can't take the host down with it. The runtime failing to allocate,
+  // past that limit or the engine's, makes run() reject with a
+  // GoMemoryError. It needs source to be something else than a compiled
+  // WebAssembly.Module, which can't be changed anymore.
+  //
+  // A trap of the module, like accessing memory out of bounds, ends the
+  // program too, and run() rejects with the WebAssembly.RuntimeError, which
+  // a call of a Go function from JS trapping, or any call after, throws
+  // too. Its stack and goStack name the Go functions it happened in, with
+  // files and lines unless source was a compiled module or a stream
+  // compiled while downloading.
+  //
+  // cpuProfile samples the program with V8's CPU profiler while it runs,
+  // Node only, into go.profiler.profile, and writes it as a pprof profile
+  // if it is a file name. Pass true, a file name or { file, interval }, the
+  // sampling interval in microseconds, 1000 by default.
+  //
+  // fuel is the compute budget of a run of a module instrumented with
+  // cmd/wasmfuel, which charges for every function call and loop iteration.
+  // The program traps once it is spent, and run() rejects with a
+  // GoFuelError, which a call of a Go function from JS spending it, or any
+  // call after, throws too. stats.fuel reports what instrumented modules
+  // used, with or without a budget.
+  //
+  // deterministic makes runs repeatable: Go's clock becomes a VirtualClock,
+  // available as go.clock, and its random data comes from a generator
+  // seeded with seed. Pass true or { seed, time, autoAdvance }.
+  //
+  // platform is the host the instance runs on, Go.platform by default. Its
+  // globals are part of the default globals.
+  //
+  // source is anything compile accepts. Construction doesn't wait for it,
+  // run() and waitLoaded() do and reject if it can't be compiled.
+  constructor(source, { debug, trace, globals = {}, replaceGlobals = false, allow, capture = false, stdin, stdout, stderr, argv0 = 'main.wasm', maxMemory, fuel, deterministic, cpuProfile, platform = Go.platform } = {}) {
+    super();
+    this.source = source;
+    this.platform = platform;
+    // compiled once, load() instantiates it again on every reset
+    this._module = compile(source, this.platform, maxMemory && (bytes => limitMemory(bytes, maxMemory)));
+    this.maxMemory = maxMemory;
+    this.fuel = fuel;
+    this.capture = capture;
+    this.stdio = { stdin, stdout, stderr };
+    this.argv0 = argv0;
+    if (deterministic) {
+      this.deterministic = Object.assign({ seed: 0 }, deterministic === true ? {} : deterministic);
+    }
+    this.globals = Object.assign({}, replaceGlobals ? {} : Object.assign({}, internalGlobal, platform.globals), globals);
+    if (allow) {
+      this.globals = restrict(this.globals, allow);
+    }
     this.timeOrigin = Date.now() - this.now;
+    if (debug || trace) {
+      this.tracer = new Go.Tracer(this, { log: !!debug, file: typeof trace === 'string' ? trace : undefined });
+    }
+    if (cpuProfile) {
+      this.profiler = new Go.Profiler(this, typeof cpuProfile === 'object' ? cpuProfile : { file: typeof cpuProfile === 'string' ? cpuProfile : undefined });
+    }
     this.golangProxy = new Proxy({}, {
       get: (target, prop) => {
         if (typeof prop === 'string'){
           let match = /^(?:runtime|syscall\/js)\.(.*)/.exec(prop);
-          if (match) return (...args) => {
-            if (debug) console.debug(`calling ${prop}(${args.join(', ')})`)
-            return this[`${match[1]}`].apply(this, args);
+          if (match) return (addr) => {
+            addr >>>= 0;
+            const fn = () => this[`${match[1]}`](addr);
+            this._pushMode('hostTime');
+            try {
+              return this.tracer ? this.tracer.call(prop, addr, fn) : fn();
+            } finally {
+              this._popMode();
+            }
           }
         }
 
@@ -25,27 +309,90 @@ class Go {
     this.env = {};
     this.instance = undefined;
     this.__loadPromise = this.load();
+    // run() reports load errors, don't let them go unhandled until then
+    this.__loadPromise.catch(() => { });
     this.exit = () => { };
   }
 
   async load() {
     this._refs = new Map();
-    
-    this._callbackShutdown = false;
+    this._pendingEvent = null;
+    this._callbackTimeouts = new Map();
+    this._nextCallbackTimeoutID = 1;
     this.exited = false;
     this.running = false;
-    const res = await WebAssembly.instantiate(this.source, { go: this.golangProxy });
-    this.instance = res.instance;
+    this._exitPromise = new Promise((resolve) => {
+      this._resolveExitPromise = resolve;
+    });
+    this._readyPromise = new Promise((resolve) => {
+      this._resolveReadyPromise = resolve;
+    });
+    this._stats = {
+      wasmTime: 0,
+      hostTime: 0,
+      peakMemory: 0,
+      values: 0,
+      writeBytes: 0,
+    };
+    this._modes = [];
+    this._modeSince = 0;
+    // the end of what was written to stderr, whether it said the runtime
+    // ran out of memory and the allocation that failed, and how the
+    // program ran out of memory if it did
+    this._stderrTail = '';
+    this._saidOutOfMemory = false;
+    this._requested = undefined;
+    this._outOfMemory = null;
+    // the fuel global's value at the start of the run
+    this._fuelStart = undefined;
+    this._outOfFuel = null;
+    this._trap = null;
+    if (this.deterministic) {
+      this.clock = new VirtualClock(this.deterministic);
+      this._randomFill = seededRandom(this.deterministic.seed);
+    }
+
+    // functions registered by the Go program through the interop package
+    this.exports = {};
+    this.global = Object.create(this.globals);
+    this.global.exports = this.exports;
+    this.global.GoPanicError = GoPanicError;
+    // what the events package calls, payloads are JSON; the names the
+    // instance or EventEmitter uses itself are refused
+    this.global.GoEvents = {
+      emit: (name, payload) => {
+        if (reservedEvents.includes(name)) {
+          throw new Error(`event ${JSON.stringify(name)} is reserved for the host`);
+        }
+        this.emit(name, JSON.parse(payload));
+      },
+      listening: name => !reservedEvents.includes(name) && this.listenerCount(name) > 0,
+    };
+    this._setupStdio();
+
+    if (this.tracer) {
+      this.tracer.reset();
+    }
+
+    this.module = await this._module;
+    checkImports(this.module);
+    this.instance = await WebAssembly.instantiate(this.module, { gojs: this.golangProxy });
+    this._stats.peakMemory = this.memRaw.byteLength;
     this._values = [
       NaN,
-      undefined,
+      0,
       null,
       true,
       false,
-      internalGlobal,
-      this.instance.exports.mem,
+      this.global,
       this,
     ];
+    this._values.forEach((v, id) => this._refs.set(v, id));
+    // number of references Go holds to each value, predefined values are
+    // never released
+    this._goRefCounts = this._values.map(() => Infinity);
+    // ids of released values, reused before growing _values
+    this._idPool = [];
   }
 
   reset() {
@@ -56,36 +403,57 @@ class Go {
     await this.__loadPromise;
   }
 
+  // resolves once main has returned control to JS for the first time, which
+  // is when functions exported from the Go program are available
+  async waitReady() {
+    await this._readyPromise;
+  }
+
   async run(...params) {
     await this.__loadPromise;
     if (this.running != false) {
       throw new Error('Go Module already running');
     }
+    const fuel = this.instance.exports.fuel;
+    if (this.fuel !== undefined && !(fuel instanceof WebAssembly.Global)) {
+      throw new Error('the fuel option needs a module instrumented by cmd/wasmfuel');
+    }
 
     this.running = true;
+    if (fuel instanceof WebAssembly.Global) {
+      if (this.fuel !== undefined) {
+        fuel.value = BigInt(this.fuel);
+      }
+      this._fuelStart = fuel.value;
+    }
 
     this.debugStartTime = this.now;
     let offset = 4096;
 
     const strPtr = (str) => {
       let ptr = offset;
-      new Uint8Array(this.memRaw, offset, str.length + 1).set(encoder.encode(str + '\0'));
-      offset += str.length + (8 - (str.length % 8));
+      const bytes = encoder.encode(str + '\0');
+      new Uint8Array(this.memRaw, offset, bytes.length).set(bytes);
+      offset += bytes.length;
+      if (offset % 8 !== 0) {
+        offset += 8 - (offset % 8);
+      }
       return ptr;
     };
 
-    const args = ['main.wasm', ...params];
+    const args = [this.argv0, ...params];
 
     const argc = args.length;
 
     // converts array of inputs into an array of pointers to the strings representations of those inputs
-    const argvPtrs = args.map(arg => JSON.stringify(arg)).map(strPtr);
+    const argvPtrs = args.map(arg => typeof arg === 'string' ? arg : JSON.stringify(arg)).map(strPtr);
+    argvPtrs.push(0);
 
     const keys = Object.keys(this.env).sort();
-    argvPtrs.push(keys.length);
     keys.forEach((key) => {
       argvPtrs.push(strPtr(`${key}=${this.env[key]}`));
     });
+    argvPtrs.push(0);
 
     // store start of pointers
     const argv = offset;
@@ -97,26 +465,350 @@ class Go {
       offset += 8;
     });
 
-    while (true) {
-      const callbackPromise = new Promise((resolve) => {
-        this._resolveCallbackPromise = () => {
-          if (this.exited) {
-            throw new Error("bad callback: Go program has already exited");
+    if (offset >= wasmMinDataAddr) {
+      throw new Error('total length of command line and environment variables exceeds limit');
+    }
+
+    if (this.profiler) {
+      await this.profiler.start();
+    }
+    let code;
+    try {
+      this._pushMode('wasmTime');
+      try {
+        this.instance.exports.run(argc, argv);
+      } catch (err) {
+        if (!this._trapped(err)) {
+          throw err;
+        }
+      } finally {
+        this._popMode();
+      }
+      this._resolveReadyPromise();
+      code = await this._exitPromise;
+    } finally {
+      if (this.profiler) {
+        await this.profiler.stop();
+      }
+    }
+    if (this.tracer && this.tracer.file) {
+      this.tracer.writeChromeTrace();
+    }
+    if (this.profiler && this.profiler.file) {
+      this.profiler.writeProfile();
+    }
+    if (this._outOfFuel) {
+      throw this._outOfFuel;
+    }
+    if (this._trap) {
+      throw this._trap;
+    }
+    if (this._outOfMemory) {
+      const { heapSize, requested } = this._outOfMemory;
+      throw new GoMemoryError(heapSize, this.maxMemory, requested);
+    }
+    const result = { code, stats: this.stats };
+    if (this.capture) {
+      result.stdout = concat(this._output[1]);
+      result.stderr = concat(this._output[2]);
+    }
+    return result;
+  }
+
+  // createStream runs the program with args like run() and returns a Node
+  // Duplex stream: what is written to it is the program's stdin and what
+  // the program writes to stdout is read from it. Both sides have
+  // backpressure, a write completes once Go has read it and Go's writes
+  // wait while the readable side is full. The stream ends when the program
+  // exits, or is destroyed with an error if run() rejects or the exit code
+  // isn't 0, which the error has as exitCode. Destroying the stream ends
+  // stdin and discards further output.
+  createStream(...args) {
+    const { Duplex } = this.platform;
+    if (!Duplex) {
+      throw new Error(`createStream needs Node streams, the ${this.platform.name} platform has none`);
+    }
+    if (this.running) {
+      throw new Error('Go Module already running');
+    }
+
+    // the last write, until Go reads it
+    let pending = null;
+    let ended = false;
+    // wake a read waiting for a write, or a write waiting for the reader
+    let wakeStdin = null;
+    let wakeStdout = null;
+    const wake = (waiting) => {
+      if (waiting) {
+        waiting();
+      }
+      return null;
+    };
+
+    const stream = new Duplex({
+      write(chunk, encoding, callback) {
+        if (ended) {
+          callback();
+          return;
+        }
+        pending = { chunk, callback };
+        wakeStdin = wake(wakeStdin);
+      },
+      final(callback) {
+        ended = true;
+        wakeStdin = wake(wakeStdin);
+        callback();
+      },
+      read() {
+        wakeStdout = wake(wakeStdout);
+      },
+      destroy(err, callback) {
+        ended = true;
+        wakeStdin = wake(wakeStdin);
+        wakeStdout = wake(wakeStdout);
+        callback(err);
+      },
+    });
+
+    const stdin = () => new Promise((resolve) => {
+      const take = () => {
+        if (pending) {
+          const { chunk, callback } = pending;
+          pending = null;
+          callback();
+          resolve(chunk);
+        } else if (ended) {
+          resolve(null);
+        } else {
+          wakeStdin = take;
+        }
+      };
+      take();
+    });
+    const stdout = (chunk) => {
+      if (stream.destroyed || stream.push(chunk)) {
+        return undefined;
+      }
+      return new Promise((resolve) => {
+        wakeStdout = resolve;
+      });
+    };
+
+    // the stream is the stdio of this run only, later runs get the options
+    const stdio = this.stdio;
+    this.stdio = Object.assign({}, stdio, { stdin, stdout });
+    this._setupStdio();
+    this.run(...args).finally(() => {
+      this.stdio = stdio;
+    }).then(({ code }) => {
+      // writes Go won't read anymore complete right away
+      ended = true;
+      if (pending) {
+        pending.callback();
+        pending = null;
+      }
+      if (code === 0) {
+        stream.push(null);
+      } else {
+        const err = new Error(`Go program exited with code ${code}`);
+        err.exitCode = code;
+        stream.destroy(err);
+      }
+    }, err => stream.destroy(err));
+    return stream;
+  }
+
+  // sets up writers and fs for the stdio and capture options
+  _setupStdio() {
+    this._output = { 1: [], 2: [] };
+    // writers of stdout and stderr replacing the platform's
+    this._writers = {};
+    [[1, this.stdio.stdout], [2, this.stdio.stderr]].forEach(([fd, writer]) => {
+      if (writer) {
+        this._writers[fd] = writer;
+      } else if (this.capture) {
+        this._writers[fd] = chunk => this._output[fd].push(chunk);
+      }
+    });
+    // what's left of the last chunk read from stdin
+    this._stdin = new Uint8Array(0);
+    this.global.fs = this.globals.fs;
+    if ((this.stdio.stdin || Object.keys(this._writers).length) && this.globals.fs) {
+      this.global.fs = this._stdioFs(this.globals.fs);
+    }
+    if (this.global.fs) {
+      this.global.fs = this._settledFs(this.global.fs);
+    }
+  }
+
+  // wraps fs so the callbacks Go passes it don't throw into the event loop
+  // when the program ran out of fuel or trapped in them, or before they
+  // were called, as a Go function JS calls does: run() rejects with it
+  _settledFs(base) {
+    return new Proxy(base, {
+      get: (target, prop) => {
+        const value = Reflect.get(target, prop);
+        if (typeof value !== 'function') {
+          return value;
+        }
+        return (...args) => {
+          const callback = args[args.length - 1];
+          if (typeof callback === 'function') {
+            args[args.length - 1] = (...results) => {
+              try {
+                return callback(...results);
+              } catch (err) {
+                if (err !== this._outOfFuel && err !== this._trap) {
+                  throw err;
+                }
+              }
+            };
           }
-          setTimeout(resolve, 0); // make sure it is asynchronous
+          return value.apply(target, args);
         };
+      },
+    });
+  }
+
+  // wraps fs so reads from stdin and writes to stdout and stderr go to the
+  // stdio options
+  _stdioFs(base) {
+    const wrapped = Object.create(base);
+    wrapped.write = (fd, buf, offset, length, position, callback) => {
+      const writer = this._writers[fd];
+      if (!writer) {
+        return base.write(fd, buf, offset, length, position, callback);
+      }
+      let written;
+      try {
+        written = writer(buf.slice(offset, offset + length));
+      } catch (err) {
+        callback(err);
+        return;
+      }
+      if (written && typeof written.then === 'function') {
+        written.then(() => callback(null, length), err => callback(err));
+      } else {
+        callback(null, length);
+      }
+    };
+    wrapped.read = (fd, buf, offset, length, position, callback) => {
+      if (fd !== 0 || !this.stdio.stdin) {
+        return base.read(fd, buf, offset, length, position, callback);
+      }
+      const copy = () => {
+        const n = Math.min(length, this._stdin.length);
+        buf.set(this._stdin.subarray(0, n), offset);
+        this._stdin = this._stdin.subarray(n);
+        callback(null, n);
+      };
+      if (this._stdin.length) {
+        copy();
+        return;
+      }
+      // a read of 0 bytes is the end of input to Go, so skip empty chunks
+      const next = () => Promise.resolve().then(() => this.stdio.stdin()).then((chunk) => {
+        if (typeof chunk === 'string') {
+          chunk = encoder.encode(chunk);
+        }
+        return chunk && !chunk.byteLength ? next() : chunk;
       });
-      this.instance.exports.run(argc, argv);
-      if (this.exited) {
-        break;
+      next().then((chunk) => {
+        this._stdin = chunk ? new Uint8Array(chunk.buffer, chunk.byteOffset, chunk.byteLength) : new Uint8Array(0);
+        copy();
+      }, err => callback(err));
+    };
+    return wrapped;
+  }
+
+  _resume() {
+    if (this.exited) {
+      throw this._outOfFuel || this._trap || new Error('Go program has already exited');
+    }
+    this._pushMode('wasmTime');
+    try {
+      this.instance.exports.resume();
+    } catch (err) {
+      if (!this._trapped(err)) {
+        throw err;
       }
-      await callbackPromise;
+    } finally {
+      this._popMode();
+    }
+  }
+
+  // _trapped reports whether err, thrown by a call into wasm, is a trap,
+  // which ends the program: running out of fuel or any other. Errors past
+  // that point are its fallout. run() rejects with the GoFuelError or the
+  // trap, and so does a call of a Go function JS made when it happened or
+  // makes later, but the timer that resumed Go mustn't throw.
+  _trapped(err) {
+    if (this._outOfFuel || this._trap) {
+      return true;
+    }
+    if (!(err instanceof WebAssembly.RuntimeError)) {
+      return false;
+    }
+    if (this.fuel !== undefined && this.instance.exports.fuel.value < 0n) {
+      this._outOfFuel = new GoFuelError(this.fuel, this.fuelUsed);
+    } else {
+      this._trap = goTrap(err, this.symbols, this.memRaw, this.instance.exports.getsp() >>> 0);
+    }
+    this._finish();
+    this._resolveReadyPromise();
+    this._resolveExitPromise(null);
+    return true;
+  }
+
+  // Time is charged to whichever of wasm or host code is innermost, so a
+  // Go callback running inside a JS call made from Go counts as wasm time.
+  _pushMode(mode) {
+    this._chargeMode();
+    this._modes.push(mode);
+  }
+
+  _popMode() {
+    this._chargeMode();
+    this._modes.pop();
+  }
+
+  _chargeMode() {
+    const now = this.now;
+    const mode = this._modes[this._modes.length - 1];
+    if (mode) {
+      this._stats[mode] += now - this._modeSince;
     }
+    this._modeSince = now;
+  }
+
+  // called by syscall/js to turn a js.Func into a callable JS function
+  _makeFuncWrapper(id) {
+    const go = this;
+    return function () {
+      const event = { id: id, this: this, args: arguments };
+      go._pendingEvent = event;
+      go._resume();
+      // the program ran out of fuel or trapped while JS called into it
+      if (go._outOfFuel || go._trap) {
+        throw go._outOfFuel || go._trap;
+      }
+      if (event.result instanceof GoPanicError) {
+        throw event.result;
+      }
+      return event.result;
+    };
   }
 
   get now() {
-    const [sec, nsec] = process.hrtime();
-    return sec * 1000 + nsec / 1000000;
+    return this.platform.now();
+  }
+
+  // symbols maps the module's functions to Go's, read on first use
+  get symbols() {
+    if (!this._symbols) {
+      this._symbols = new Go.Symbols(this.module, moduleBytes.get(this.module));
+    }
+    return this._symbols;
   }
 
   get mem() {
@@ -131,6 +823,37 @@ class Go {
     return !!this.instance;
   }
 
+  // execution statistics of the current instance: ms spent in wasm and in
+  // Go.js imports, peak linear memory in bytes, JS values handed to Go and
+  // bytes written by the runtime through wasmWrite
+  get stats() {
+    const stats = Object.assign({}, this._stats);
+    if (this.fuelUsed !== undefined) {
+      stats.fuel = this.fuelUsed;
+    }
+    return stats;
+  }
+
+  // fuel used by the last run of a module instrumented by cmd/wasmfuel
+  get fuelUsed() {
+    if (this._fuelStart === undefined) {
+      return undefined;
+    }
+    return Number(this._fuelStart - this.instance.exports.fuel.value);
+  }
+
+  // number of JS values Go currently holds references to
+  get liveValues() {
+    return this._values ? this._values.length - this._idPool.length : 0;
+  }
+
+  // Go's stack pointer can move if Go code runs inside an import (e.g. a
+  // getter calling back into an exported function), so imports that call
+  // into JS re-read it before storing results.
+  get sp() {
+    return this.instance.exports.getsp() >>> 0;
+  }
+
   //#region golang interop functions
 
   debug(...args) {
@@ -141,55 +864,133 @@ class Go {
   // func wasmExit(code int32)
   wasmExit(addr) {
     const code = this.getInt32(addr + 8);
+    if (code !== 0 && this._ranOutOfMemory()) {
+      this._outOfMemory = { heapSize: this.memRaw.byteLength, requested: this._requested };
+    }
+    this._finish();
+    this.exit(code);
+    this._resolveReadyPromise();
+    this._resolveExitPromise(code);
+    this.emit('exit', code);
+  }
+  // _finish releases what the program held once it ended
+  _finish() {
     this.exited = true;
-    delete this._inst;
+    this.running = false;
+    // timers of goroutines still sleeping would resume an exited program
+    this._callbackTimeouts.forEach(timeout => (this.clock || globalThis).clearTimeout(timeout));
+    this._callbackTimeouts.clear();
     delete this._values;
     delete this._refs;
-    this.exit(code); // TODO: implement exit
+    delete this._goRefCounts;
+    delete this._idPool;
   }
   // func wasmWrite(fd uintptr, p unsafe.Pointer, n int32)
   wasmWrite(addr) {
     const fd = this.getInt64(addr + 8);
     const p = this.getInt64(addr + 16);
     const n = this.getInt32(addr + 24);
-    fs.writeSync(fd, new Uint8Array(this.memRaw, p, n));
+    const buf = new Uint8Array(this.memRaw, p, n);
+    if (fd === 2 && !this._saidOutOfMemory) {
+      this._watchStderr(buf);
+    }
+    if (this._writers[fd]) {
+      // the runtime can't wait, but a failing writer mustn't go unhandled
+      Promise.resolve(this._writers[fd](buf.slice())).catch(() => { });
+    } else {
+      this.platform.writeSync(fd, buf);
+    }
+    this._stats.writeBytes += n;
+  }
+  // _ranOutOfMemory tells whether a program failing now failed because
+  // memory couldn't grow: the runtime said it couldn't allocate, and what's
+  // left below maxMemory or the engine's limit is less than a heap arena or
+  // the allocation it wanted. Programs only printing the runtime's message,
+  // or failing otherwise close to the limit, keep their exit code.
+  _ranOutOfMemory() {
+    if (!this._saidOutOfMemory && this._requested === undefined) {
+      return false;
+    }
+    const limit = this.maxMemory ? Math.floor(this.maxMemory / wasmPageSize) * wasmPageSize : maxWasmMemory;
+    return limit - this.memRaw.byteLength < Math.max(heapArenaBytes, this._requested || 0);
   }
-  // func nanotime() int64
-  nanotime(addr) {
-    this.setInt64(addr + 8, (this.timeOrigin + this.now) * 1000000);
+  // watches stderr for the runtime's "fatal error: out of memory" and the
+  // size of the allocation that failed, which it prints before
+  _watchStderr(buf) {
+    // the runtime prints a line in pieces, look at them together
+    const text = this._stderrTail + decoder.decode(buf);
+    this._stderrTail = text.slice(-256);
+    const requested = /cannot allocate (\d+)-byte block/.exec(text);
+    if (requested) {
+      this._requested = Number(requested[1]);
+    }
+    if (/fatal error: out of memory/.test(text)) {
+      this._saidOutOfMemory = true;
+    }
+  }
+  // func resetMemoryDataView()
+  resetMemoryDataView(addr) {
+    // mem is re-created from the current buffer on every access, this is
+    // only a notification that memory grew
+    this._stats.peakMemory = Math.max(this._stats.peakMemory, this.memRaw.byteLength);
+  }
+  // func nanotime1() int64
+  nanotime1(addr) {
+    const msec = this.clock ? this.clock.wallTime : this.timeOrigin + this.now;
+    this.setInt64(addr + 8, msec * 1000000);
   }
   // func walltime() (sec int64, nsec int32)
   walltime(addr) {
-    const msec = (new Date).getTime();
+    const msec = this.clock ? this.clock.wallTime : (new Date).getTime();
     this.setInt64(addr + 8, msec / 1000);
     this.setInt32(addr + 16, (msec % 1000) * 1000000);
   };
 
-  // func scheduleCallback(delay int64) int32
-  scheduleCallback(addr) {
+  // func scheduleTimeoutEvent(delay int64) int32
+  scheduleTimeoutEvent(addr) {
     const id = this._nextCallbackTimeoutID;
     this._nextCallbackTimeoutID++;
-    this._callbackTimeouts.set(id, setTimeout(
-      () => { this._resolveCallbackPromise(); },
-      this.getInt64(addr + 8) + 1, // setTimeout has been seen to fire up to 1 millisecond early
+    const timers = this.clock || globalThis;
+    this._callbackTimeouts.set(id, timers.setTimeout(
+      () => {
+        this._resume();
+        while (this._callbackTimeouts.has(id)) {
+          // Go failed to register the timeout event, try again
+          // (https://github.com/golang/go/issues/28975)
+          this._resume();
+        }
+      },
+      this.getInt64(addr + 8),
     ));
     this.setInt32(addr + 16, id);
   };
 
-  // func clearScheduledCallback(id int32)
-  clearScheduledCallback(addr) {
-    const id = this.mem.getInt32(addr + 8, true);
-    clearTimeout(this._callbackTimeouts.get(id));
+  // func clearTimeoutEvent(id int32)
+  clearTimeoutEvent(addr) {
+    const id = this.getInt32(addr + 8);
+    (this.clock || globalThis).clearTimeout(this._callbackTimeouts.get(id));
     this._callbackTimeouts.delete(id);
   };
 
   // func getRandomData(r []byte)
   getRandomData(sp) {
-    crypto.randomFillSync(this.loadSlice(sp + 8));
+    (this._randomFill || this.platform.randomFill)(this.loadSlice(sp + 8));
   };
   //#endregion
 
   //#region syscall/js
+  // func finalizeRef(v ref)
+  finalizeRef(addr) {
+    const id = this.getUint32(addr + 8);
+    this._goRefCounts[id]--;
+    if (this._goRefCounts[id] === 0) {
+      const v = this._values[id];
+      this._values[id] = null;
+      this._refs.delete(v);
+      this._idPool.push(id);
+    }
+  }
+
   // func stringVal(value string) ref
   stringVal(addr) {
     this.storeValue(addr + 24, this.loadString(addr + 8));
@@ -199,10 +1000,10 @@ class Go {
   valueGet(addr) {
     const obj = this.loadValue(addr + 8);
     const prop = this.loadString(addr + 16);
-    
-    const val = obj[prop];
-    
-    this.storeValue(addr + 32, val);
+
+    const val = Reflect.get(obj, prop);
+
+    this.storeValue(this.sp + 32, val);
   }
 
   // func valueSet(v ref, p string, x ref)
@@ -210,22 +1011,29 @@ class Go {
     const obj = this.loadValue(addr + 8);
     const prop = this.loadString(addr + 16);
     const val = this.loadValue(addr + 32);
-    obj[prop] = val;
+    Reflect.set(obj, prop, val);
+  }
+
+  // func valueDelete(v ref, p string)
+  valueDelete(addr) {
+    const obj = this.loadValue(addr + 8);
+    const prop = this.loadString(addr + 16);
+    Reflect.deleteProperty(obj, prop);
   }
 
   // func valueIndex(v ref, i int) ref
   valueIndex(addr) {
     const obj = this.loadValue(addr + 8);
     const idx = this.getInt64(addr + 16);
-    this.storeValue(addr + 24, obj[idx]);
+    this.storeValue(addr + 24, Reflect.get(obj, idx));
   }
 
   // valueSetIndex(v ref, i int, x ref)
   valueSetIndex(addr) {
     const obj = this.loadValue(addr + 8);
     const idx = this.getInt64(addr + 16);
-    const val = this.loadValue(addr + 32);
-    obj[idx] = val;
+    const val = this.loadValue(addr + 24);
+    Reflect.set(obj, idx, val);
   }
 
   // func valueCall(v ref, m string, args []ref) (ref, bool)
@@ -233,12 +1041,15 @@ class Go {
     try {
       const obj = this.loadValue(addr + 8);
       const name = this.loadString(addr + 16);
-      const method = obj[name];
+      const method = Reflect.get(obj, name);
       const args = this.loadSliceOfValues(addr + 32);
-      this.storeValue(addr + 56, Reflect.apply(method, obj, args));
+      const result = Reflect.apply(method, obj, args);
+      addr = this.sp;
+      this.storeValue(addr + 56, result);
       this.setUint8(addr + 64, 1);
     } catch (err) {
-      this.storeValue(addr + 56, err);
+      addr = this.sp;
+      this.storeValue(addr + 56, toError(err));
       this.setUint8(addr + 64, 0);
     }
   }
@@ -248,10 +1059,13 @@ class Go {
     try {
       const obj = this.loadValue(addr + 8);
       const args = this.loadSliceOfValues(addr + 16);
-      this.storeValue(addr + 40, Reflect.apply(obj, undefined, args));
+      const result = Reflect.apply(obj, undefined, args);
+      addr = this.sp;
+      this.storeValue(addr + 40, result);
       this.setUint8(addr + 48, 1);
     } catch (err) {
-      this.storeValue(addr + 40, err);
+      addr = this.sp;
+      this.storeValue(addr + 40, toError(err));
       this.setUint8(addr + 48, 0);
     }
   }
@@ -261,10 +1075,13 @@ class Go {
     try {
       const obj = this.loadValue(addr + 8);
       const args = this.loadSliceOfValues(addr + 16);
-      this.storeValue(addr + 40, Reflect.construct(obj, args));
+      const result = Reflect.construct(obj, args);
+      addr = this.sp;
+      this.storeValue(addr + 40, result);
       this.setUint8(addr + 48, 1);
     } catch (err) {
-      this.storeValue(addr + 40, err);
+      addr = this.sp;
+      this.storeValue(addr + 40, toError(err));
       this.setUint8(addr + 48, 0);
     }
   }
@@ -295,7 +1112,35 @@ class Go {
   valueInstanceOf(addr) {
     const val = this.loadValue(addr + 8);
     const type = this.loadValue(addr + 16);
-    this.setUint8(addr + 24, val instanceof type);
+    this.setUint8(addr + 24, val instanceof type ? 1 : 0);
+  }
+
+  // func copyBytesToGo(dst []byte, src ref) (int, bool)
+  copyBytesToGo(addr) {
+    const dst = this.loadSlice(addr + 8);
+    const src = this.loadValue(addr + 32);
+    if (!(src instanceof Uint8Array || src instanceof Uint8ClampedArray)) {
+      this.setUint8(addr + 48, 0);
+      return;
+    }
+    const toCopy = src.subarray(0, dst.length);
+    dst.set(toCopy);
+    this.setInt64(addr + 40, toCopy.length);
+    this.setUint8(addr + 48, 1);
+  }
+
+  // func copyBytesToJS(dst ref, src []byte) (int, bool)
+  copyBytesToJS(addr) {
+    const dst = this.loadValue(addr + 8);
+    const src = this.loadSlice(addr + 16);
+    if (!(dst instanceof Uint8Array || dst instanceof Uint8ClampedArray)) {
+      this.setUint8(addr + 48, 0);
+      return;
+    }
+    const toCopy = src.subarray(0, dst.length);
+    dst.set(toCopy);
+    this.setInt64(addr + 40, toCopy.length);
+    this.setUint8(addr + 48, 1);
   }
   //#endregion
 
@@ -308,7 +1153,7 @@ class Go {
   }
 
   getInt64(addr) {
-    const low = this.getInt32(addr + 0);
+    const low = this.getUint32(addr + 0);
     const high = this.getInt32(addr + 4);
     return low + high * 4294967296;
   }
@@ -345,7 +1190,7 @@ class Go {
   loadSlice(addr) {
     const array = this.getInt64(addr + 0);
     const len = this.getInt64(addr + 8);
-    
+
     return new Uint8Array(this.memRaw, array, len);
   }
 
@@ -366,8 +1211,11 @@ class Go {
   }
 
   loadValue(addr) {
-    // first try loading float value
+    // first try loading float value, 0 is how Go encodes undefined
     const f = this.getFloat64(addr);
+    if (f === 0) {
+      return undefined;
+    }
     if (!isNaN(f)) {
       return f;
     }
@@ -379,7 +1227,7 @@ class Go {
   storeValue(addr, v) {
     const nanHead = 0x7FF80000;
 
-    if (typeof v === "number") {
+    if (typeof v === "number" && v !== 0) {
       if (isNaN(v)) {
         this.setUint32(addr + 4, nanHead);
         this.setUint32(addr, 0);
@@ -389,43 +1237,40 @@ class Go {
       return;
     }
 
-    switch (v) {
-      case undefined:
-        this.setUint32(addr + 4, nanHead);
-        this.setUint32(addr, 1);
-        return;
-      case null:
-        this.setUint32(addr + 4, nanHead);
-        this.setUint32(addr, 2);
-        return;
-      case true:
-        this.setUint32(addr + 4, nanHead);
-        this.setUint32(addr, 3);
-        return;
-      case false:
-        this.setUint32(addr + 4, nanHead);
-        this.setUint32(addr, 4);
-        return;
+    if (v === undefined) {
+      this.setFloat64(addr, 0);
+      return;
     }
 
     let ref = this._refs.get(v);
 
     if (ref === undefined) {
-      ref = this._values.length;
-      this._values.push(v);
+      ref = this._idPool.pop();
+      if (ref === undefined) {
+        ref = this._values.length;
+      }
+      this._values[ref] = v;
+      this._goRefCounts[ref] = 0;
       this._refs.set(v, ref);
+      this._stats.values++;
     }
+    this._goRefCounts[ref]++;
 
     let typeFlag = 0;
     switch (typeof v) {
+      case "object":
+        if (v !== null) {
+          typeFlag = 1;
+        }
+        break;
       case "string":
-        typeFlag = 1;
+        typeFlag = 2;
         break;
       case "symbol":
-        typeFlag = 2;
+        typeFlag = 3;
         break;
       case "function":
-        typeFlag = 3;
+        typeFlag = 4;
         break;
     }
     this.setUint32(addr + 4, nanHead | typeFlag);
@@ -435,31 +1280,26 @@ class Go {
   //#endregion
 }
 
-Go._makeCallbackHelper = (id, pendingCallbacks, go) => {
-  return function() {
-    pendingCallbacks.push({ id: id, args: arguments });
-    go._resolveCallbackPromise();
-  };
-}
-
-Go._makeEventCallbackHelper = (preventDefault, stopPropagation, stopImmediatePropagation, fn) => {
-  return function(event) {
-    if (preventDefault) {
-      event.preventDefault();
-    }
-    if (stopPropagation) {
-      event.stopPropagation();
-    }
-    if (stopImmediatePropagation) {
-      event.stopImmediatePropagation();
-    }
-    fn(event);
-  };
-}
-
+Go.GoPanicError = GoPanicError;
+Go.GoMemoryError = GoMemoryError;
+Go.GoFuelError = GoFuelError;
+Go.GoImportError = GoImportError;
+Go.hostFunctions = hostFunctions;
+Go.importReleases = importReleases;
+Go.VirtualClock = VirtualClock;
+Go.compile = (source, platform = Go.platform) => compile(source, platform);
+
+// the globals the Go runtime needs and that Go code can reach through
+// js.Global() by default
 const internalGlobal = {
   Object,
   Array,
+  Error,
+  Date,
+  Math,
+  JSON,
+  Promise,
+  console,
   Int8Array,
   Int16Array,
   Int32Array,
@@ -468,9 +1308,31 @@ const internalGlobal = {
   Uint32Array,
   Float32Array,
   Float64Array,
-  process,
-  fs,
   Go,
 };
 
-module.exports = Go;
\ No newline at end of file
+// the globals instances start from, a frozen copy so changing them can't
+// reach into instances
+Go.defaultGlobals = Object.freeze(Object.assign({}, internalGlobal));
+
+// usePlatform sets the platform Go instances created afterwards run on by
+// default, and the defaultGlobals they start from to include its fs,
+// process and path. Instances given a platform, and those created before,
+// keep theirs.
+Go.usePlatform = (platform) => {
+  Go.platform = platform;
+  Go.defaultGlobals = Object.freeze(Object.assign({}, internalGlobal, platform.globals));
+};
+
+// the smallest allow list a Go program printing to stdout runs with
+Go.runtimeGlobals = ['Object', 'Array', 'Uint8Array', 'fs.write', 'fs.constants'];
+
+if (commonJS) {
+  Go.Tracer = require('./Tracer');
+  Go.Symbols = require('./Symbols');
+  Go.Profiler = require('./Profiler');
+  Go.usePlatform(require('./platform/node'));
+  module.exports = Go;
+} else {
+  globalThis.Go = Go;
+}
\ No newline at end of file
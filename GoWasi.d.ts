@@ -0,0 +1,49 @@
+import Go = require('./Go');
+
+/** Runs a Go program built with GOOS=wasip1 with the Go class's API. */
+declare class GoWasi {
+  constructor(source: Go.Source | PromiseLike<Go.Source>, options?: GoWasi.Options);
+
+  env: Record<string, string>;
+  exit: (code: number) => void;
+  readonly exited: boolean;
+  readonly running: boolean;
+  readonly loaded: boolean;
+  readonly stats: GoWasi.Stats;
+
+  load(): Promise<void>;
+  reset(): void;
+  waitLoaded(): Promise<void>;
+  run(...args: unknown[]): Promise<GoWasi.RunResult>;
+}
+
+declare namespace GoWasi {
+  interface Options {
+    /** Collect stdout and stderr and return them from run(). */
+    capture?: boolean;
+    /** Guest directories mapped to host directories, e.g. { '/': root }. */
+    preopens?: Record<string, string>;
+    /** Host file descriptors for the guest's stdio. */
+    stdin?: number;
+    stdout?: number;
+    stderr?: number;
+    /** The program name Go sees as os.Args[0], 'main.wasm' by default. */
+    argv0?: string;
+  }
+
+  interface Stats {
+    /** Milliseconds spent running wasm, including WASI calls. */
+    wasmTime: number;
+    /** Size of linear memory in bytes at exit. */
+    peakMemory: number;
+  }
+
+  interface RunResult {
+    code: number;
+    stats: Stats;
+    stdout?: string;
+    stderr?: string;
+  }
+}
+
+export = GoWasi;
@@ -0,0 +1,150 @@
+const fs = require('fs');
+const os = require('os');
+const path = require('path');
+const { WASI } = require('wasi');
+const Go = require('./Go');
+
+// FDFLAG_NONBLOCK of WASI's fdflags
+const fdflagNonblock = 0x4;
+
+// GoWasi runs Go programs built with GOOS=wasip1 on Node's WASI
+// implementation. It mirrors the Go class: construct it with a source,
+// set env and exit, then run(...args) resolves to { code, stats } plus
+// stdout and stderr when capture is set.
+//
+// preopens maps guest directories to host directories, e.g. { '/': root }.
+// stdin, stdout and stderr are host file descriptors for the guest's stdio.
+// argv0 is the program name Go sees as os.Args[0].
+class GoWasi {
+  constructor(source, { capture = false, preopens = {}, stdin = 0, stdout = 1, stderr = 2, argv0 = 'main.wasm' } = {}) {
+    this.source = source;
+    this._module = Go.compile(source);
+    this.capture = capture;
+    this.argv0 = argv0;
+    this.preopens = preopens;
+    this.stdio = { stdin, stdout, stderr };
+    this.env = {};
+    this.instance = undefined;
+    this.__loadPromise = this.load();
+    this.__loadPromise.catch(() => { });
+    this.exit = () => { };
+  }
+
+  async load() {
+    this.exited = false;
+    this.running = false;
+    this._stats = {
+      wasmTime: 0,
+      peakMemory: 0,
+    };
+    this.module = await this._module;
+  }
+
+  reset() {
+    this.__loadPromise = this.load();
+  }
+
+  async waitLoaded() {
+    await this.__loadPromise;
+  }
+
+  async run(...params) {
+    await this.__loadPromise;
+    if (this.running != false) {
+      throw new Error('Go Module already running');
+    }
+
+    this.running = true;
+
+    const args = [this.argv0, ...params].map(arg => typeof arg === 'string' ? arg : JSON.stringify(arg));
+
+    let stdio = this.stdio;
+    let tmp;
+    if (this.capture) {
+      tmp = fs.mkdtempSync(path.join(os.tmpdir(), 'go-wasi-'));
+      stdio = Object.assign({}, stdio, {
+        stdout: fs.openSync(path.join(tmp, 'stdout'), 'w+'),
+        stderr: fs.openSync(path.join(tmp, 'stderr'), 'w+'),
+      });
+    }
+
+    const wasi = new WASI({
+      version: 'preview1',
+      args,
+      env: this.env,
+      preopens: this.preopens,
+      returnOnExit: true,
+      stdin: stdio.stdin,
+      stdout: stdio.stdout,
+      stderr: stdio.stderr,
+    });
+
+    const result = {};
+    try {
+      // Go makes its files non-blocking and waits for them in poll_oneoff
+      // when a read would block, but Node's WASI doesn't report the flag
+      // back from fd_fdstat_get, so Go takes EAGAIN for a failed read, e.g.
+      // on a child process's stdin. Report the flags Go set.
+      const nonblocking = new Set();
+      const imports = Object.assign({}, wasi.wasiImport, {
+        fd_fdstat_set_flags: (fd, flags) => {
+          const errno = wasi.wasiImport.fd_fdstat_set_flags(fd, flags);
+          if (errno === 0 && flags & fdflagNonblock) {
+            nonblocking.add(fd);
+          } else if (errno === 0) {
+            nonblocking.delete(fd);
+          }
+          return errno;
+        },
+        fd_fdstat_get: (fd, ptr) => {
+          const errno = wasi.wasiImport.fd_fdstat_get(fd, ptr);
+          if (errno === 0 && nonblocking.has(fd)) {
+            // fs_flags is the u16 at offset 2 of the fdstat
+            const view = new DataView(this.memRaw);
+            view.setUint16(ptr + 2, view.getUint16(ptr + 2, true) | fdflagNonblock, true);
+          }
+          return errno;
+        },
+      });
+      this.instance = await WebAssembly.instantiate(this.module, { wasi_snapshot_preview1: imports });
+      const start = this.now;
+      result.code = wasi.start(this.instance);
+      this._stats.wasmTime = this.now - start;
+      this._stats.peakMemory = this.memRaw.byteLength;
+      result.stats = this.stats;
+    } finally {
+      this.exited = true;
+      this.running = false;
+      if (this.capture) {
+        fs.closeSync(stdio.stdout);
+        fs.closeSync(stdio.stderr);
+        result.stdout = fs.readFileSync(path.join(tmp, 'stdout'), 'utf8');
+        result.stderr = fs.readFileSync(path.join(tmp, 'stderr'), 'utf8');
+        fs.rmSync(tmp, { recursive: true, force: true });
+      }
+    }
+    this.exit(result.code);
+    return result;
+  }
+
+  get now() {
+    const [sec, nsec] = process.hrtime();
+    return sec * 1000 + nsec / 1000000;
+  }
+
+  get memRaw() {
+    return this.instance.exports.memory.buffer;
+  }
+
+  get loaded() {
+    return !!this.module;
+  }
+
+  // execution statistics of the last run: ms spent in wasm, including WASI
+  // calls, and linear memory size in bytes at exit
+  get stats() {
+    return Object.assign({}, this._stats);
+  }
+}
+
+module.exports = GoWasi;
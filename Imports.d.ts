@@ -0,0 +1,32 @@
+/** The functions Go programs import from JS and what Go.js knows of them. */
+declare namespace Imports {
+  /** The gojs imports Go.js implements, like 'syscall/js.valueGet'. */
+  const hostFunctions: readonly string[];
+  /** The Go release that introduced each import Go has had, like 'go1.13' for 'syscall/js.copyBytesToJS'. */
+  const importReleases: Readonly<Record<string, string>>;
+  /** The latest Go release importReleases was checked against. */
+  const newestGo: string;
+  /** The release introducing name, or 'not in Go up to' newestGo. */
+  function importRelease(name: string): string;
+  /** The Go that built module, like go1.21.5, if its producers section says. */
+  function goVersion(module: WebAssembly.Module): string | undefined;
+  /** Throws a GoImportError if module imports what Go.js doesn't implement. */
+  function checkImports(module: WebAssembly.Module): void;
+
+  /**
+   * What run() and waitLoaded() reject with when the module imports
+   * functions Go.js doesn't implement.
+   */
+  class GoImportError extends Error {
+    constructor(missing: string[], goVersion?: string);
+    name: 'GoImportError';
+    /** The imports, as module.name. */
+    missing: string[];
+    /** The Go that built the module, like go1.21.5, if it says. */
+    goVersion?: string;
+    /** The Go release that introduced each of missing, see importRelease. */
+    releases: string[];
+  }
+}
+
+export = Imports;
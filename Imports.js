@@ -0,0 +1,147 @@
+// Imports are the functions Go programs import from JS and what Go.js
+// knows of them: which it implements, which Go release introduced each,
+// and the check of a module's imports Go.js runs when it loads one.
+
+const { readLEB } = typeof module === 'object' && module.exports ? require('./WasmBinary') : globalThis.GoWasmBinary;
+
+// hostFunctions are the functions of the gojs import module Go.js
+// implements, named after the Go functions they stand for. cmd/gojscheck
+// reads this list to check binaries without running them, one entry per
+// line, and fails on lines it can't read.
+const hostFunctions = [
+  'runtime.wasmExit',
+  'runtime.wasmWrite',
+  'runtime.resetMemoryDataView',
+  'runtime.nanotime1',
+  'runtime.walltime',
+  'runtime.scheduleTimeoutEvent',
+  'runtime.clearTimeoutEvent',
+  'runtime.getRandomData',
+  'syscall/js.finalizeRef',
+  'syscall/js.stringVal',
+  'syscall/js.valueGet',
+  'syscall/js.valueSet',
+  'syscall/js.valueDelete',
+  'syscall/js.valueIndex',
+  'syscall/js.valueSetIndex',
+  'syscall/js.valueCall',
+  'syscall/js.valueInvoke',
+  'syscall/js.valueNew',
+  'syscall/js.valueLength',
+  'syscall/js.valuePrepareString',
+  'syscall/js.valueLoadString',
+  'syscall/js.valueInstanceOf',
+  'syscall/js.copyBytesToGo',
+  'syscall/js.copyBytesToJS',
+];
+
+// importReleases maps the functions Go imports from JS to the Go release
+// that introduced them under that name, from the wasm_exec.js of each
+// release, including names later releases dropped. Up to Go 1.20 they were
+// imported from go, since then from gojs. newestGo is the latest release
+// the table was checked against; cmd/gojscheck reads both, the table one
+// entry per line like hostFunctions.
+const importReleases = {
+  'runtime.wasmExit': 'go1.11',
+  'runtime.wasmWrite': 'go1.11',
+  'runtime.nanotime': 'go1.11',
+  'runtime.walltime': 'go1.11',
+  'runtime.scheduleCallback': 'go1.11',
+  'runtime.clearScheduledCallback': 'go1.11',
+  'runtime.getRandomData': 'go1.11',
+  'runtime.scheduleTimeoutEvent': 'go1.12',
+  'runtime.clearTimeoutEvent': 'go1.12',
+  'runtime.nanotime1': 'go1.14',
+  'runtime.resetMemoryDataView': 'go1.14',
+  'runtime.walltime1': 'go1.16',
+  'syscall/js.stringVal': 'go1.11',
+  'syscall/js.valueGet': 'go1.11',
+  'syscall/js.valueSet': 'go1.11',
+  'syscall/js.valueIndex': 'go1.11',
+  'syscall/js.valueSetIndex': 'go1.11',
+  'syscall/js.valueCall': 'go1.11',
+  'syscall/js.valueInvoke': 'go1.11',
+  'syscall/js.valueNew': 'go1.11',
+  'syscall/js.valueLength': 'go1.11',
+  'syscall/js.valuePrepareString': 'go1.11',
+  'syscall/js.valueLoadString': 'go1.11',
+  'syscall/js.valueInstanceOf': 'go1.11',
+  'syscall/js.copyBytesToGo': 'go1.13',
+  'syscall/js.copyBytesToJS': 'go1.13',
+  'syscall/js.finalizeRef': 'go1.14',
+  'syscall/js.valueDelete': 'go1.14',
+};
+const newestGo = 'go1.27';
+
+// importRelease describes when Go started importing name, as in
+// importReleases or as newer than the Go releases the table knows
+const importRelease = name => importReleases[name] || `not in Go up to ${newestGo}`;
+
+// goVersion returns the version of Go that built module, like go1.21.5,
+// as its producers section says, or undefined.
+const goVersion = (module) => {
+  const [section] = WebAssembly.Module.customSections(module, 'producers');
+  if (!section) {
+    return undefined;
+  }
+  const bytes = new Uint8Array(section);
+  const decoder = new TextDecoder();
+  let offset = 0;
+  const name = () => {
+    let len;
+    [len, offset] = readLEB(bytes, offset);
+    offset += len;
+    return decoder.decode(bytes.subarray(offset - len, offset));
+  };
+  let fields;
+  [fields, offset] = readLEB(bytes, offset);
+  for (; fields > 0; fields--) {
+    const field = name();
+    let values;
+    [values, offset] = readLEB(bytes, offset);
+    for (; values > 0; values--) {
+      const [value, version] = [name(), name()];
+      if (field === 'language' && value === 'Go') {
+        return version;
+      }
+    }
+  }
+  return undefined;
+};
+
+// checkImports throws a GoImportError if module imports functions from Go
+// that Go.js doesn't implement, which would otherwise fail only once Go
+// calls them. Modules of Go before 1.21 import from go instead of gojs.
+const checkImports = (module) => {
+  const missing = WebAssembly.Module.imports(module)
+    .filter(({ module: from, name }) => from === 'go' || from === 'gojs' && !hostFunctions.includes(name))
+    .map(({ module: from, name }) => `${from}.${name}`);
+  if (missing.length) {
+    throw new GoImportError(missing, goVersion(module));
+  }
+};
+
+// GoImportError is what run() and waitLoaded() reject with when the module
+// needs functions from Go.js it doesn't implement, usually because it was
+// built by a newer Go than Go.js supports. missing are the imports as
+// module.name, goVersion the Go that built the module, if it says, and
+// releases when Go started importing each of missing, see importRelease.
+class GoImportError extends Error {
+  constructor(missing, goVersion) {
+    const releases = missing.map(name => importRelease(name.slice(name.indexOf('.') + 1)));
+    const imports = missing.map((name, i) => `${name} (${releases[i]})`);
+    super(`module built by ${goVersion || 'an unknown version of Go'} imports what Go.js doesn't implement: ${imports.join(', ')}`);
+    this.name = 'GoImportError';
+    this.missing = missing;
+    this.goVersion = goVersion;
+    this.releases = releases;
+  }
+}
+
+const Imports = { hostFunctions, importReleases, newestGo, importRelease, goVersion, checkImports, GoImportError };
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = Imports;
+} else {
+  globalThis.GoImports = Imports;
+}
@@ -1,26 +1,150 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"math/big"
 	"os"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func fib(n int) int {
-	if n <= 2 {
-		return 1
-	}
-	return fib(n-1) + fib(n-2)
+//go:generate go run ./cmd/gojsbuild -generate .
+
+var (
+	algo     = flag.String("algo", "recursive", "algorithm: "+strings.Join(algorithmNames(), ", "))
+	format   = flag.String("format", "text", "output format: text or json")
+	asJSON   = flag.Bool("json", false, "same as -format json")
+	memStats = flag.Bool("memstats", false, "include runtime.MemStats in the JSON output")
+	timed    = flag.Bool("timing", false, "include when and how long fib ran in the JSON output")
+	rpc      = flag.Bool("rpc", false, "answer JSON-RPC 2.0 requests read from stdin, one per line, until it is closed")
+	batch    = flag.Bool("batch", false, "read n, optionally followed by an algorithm, from each line of stdin and write a result per line")
+)
+
+// result is the JSON output of a run.
+type result struct {
+	N        int               `json:"n"`
+	Algo     string            `json:"algo"`
+	Fib      *big.Int          `json:"fib"`
+	MemStats *runtime.MemStats `json:"memStats,omitempty"`
+	Timing   *timing           `json:"timing,omitempty"`
 }
 
+// timing records when fib started and how long it took. Under Go.js's
+// deterministic mode both come from its virtual clock and are the same on
+// every run.
+type timing struct {
+	Start     time.Time `json:"start"`
+	ElapsedNs int64     `json:"elapsedNs"`
+}
+
+// serve, when set, registers an http handler with the host and blocks, so
+// that JS can call it and the exported functions, instead of printing a
+// single result if the flags ask for it. It is only available when running
+// under Go.js.
+var serve func() bool
+
 func main() {
+	flag.Parse()
+	if serve != nil && serve() {
+		return
+	}
+	if *rpc {
+		if err := serveRPC(os.Stdin, os.Stdout); err != nil {
+			fail("reading requests: %v", err)
+		}
+		return
+	}
+
+	if *asJSON {
+		*format = "json"
+	}
+	if *format != "text" && *format != "json" {
+		fail("unknown format %q", *format)
+	}
+	if *batch {
+		failed, err := runBatch(os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			fail("reading input: %v", err)
+		}
+		if failed > 0 {
+			fail("failed lines: %d", failed)
+		}
+		return
+	}
+
 	var n int
-	if len(os.Args) > 1 {
-		n, _ = strconv.Atoi(os.Args[1])
+	if flag.NArg() > 0 {
+		n, _ = strconv.Atoi(flag.Arg(0))
 	}
 	if n == 0 {
 		n = 10
 	}
+	res, err := compute(n, *algo)
+	if err != nil {
+		fail("%v", err)
+	}
+	if err := writeResult(os.Stdout, res); err != nil {
+		fail("%v", err)
+	}
+}
+
+// compute computes fib(n) with the named algorithm, adding memory stats
+// and timing to the result if the flags ask for them.
+func compute(n int, name string) (*result, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("n must not be negative, got %d", n)
+	}
+	f, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown algorithm %q", name)
+	}
+	start := time.Now()
+	v, err := f(n)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("fib(%d): %v", n, err)
+	}
+
+	res := &result{N: n, Algo: name, Fib: v}
+	if *memStats {
+		res.MemStats = new(runtime.MemStats)
+		runtime.ReadMemStats(res.MemStats)
+	}
+	if *timed {
+		res.Timing = &timing{Start: start.UTC(), ElapsedNs: elapsed.Nanoseconds()}
+	}
+	return res, nil
+}
+
+// writeResult writes res to w in -format.
+func writeResult(w io.Writer, res *result) error {
+	switch *format {
+	case "text":
+		_, err := fmt.Fprintf(w, "fib(%d) = %s\n", res.N, res.Fib)
+		return err
+	case "json":
+		return json.NewEncoder(w).Encode(res)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func algorithmNames() []string {
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	fmt.Printf("fib(%d) = %d\n", n, fib(n))
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
 }
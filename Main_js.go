@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"syscall/js"
+
+	"go-to-js/nodehttp"
+)
+
+var serveFlag = flag.Bool("serve", false, "register an http handler serving /fib with the host and wait for calls of it and the exported functions")
+
+func init() {
+	serve = func() bool {
+		if !*serveFlag {
+			return false
+		}
+		nodehttp.Handle("http", fibHandler())
+		select {}
+	}
+}
+
+// exportedFib computes fib(n) with the algorithm given, -algo by default.
+// Results that don't fit a JS number exactly are returned as decimal
+// strings.
+//
+//gojs:export fib
+func exportedFib(n int, algorithm ...string) (js.Value, error) {
+	name := *algo
+	if len(algorithm) > 0 {
+		name = algorithm[0]
+	}
+	res, err := compute(n, name)
+	if err != nil {
+		return js.Value{}, err
+	}
+	return js.ValueOf(exactNumber(res.Fib)), nil
+}
+
+// fibString computes fib(n) with the named algorithm, returning it as a
+// decimal string so that large results stay exact.
+//
+//gojs:export
+func fibString(n int, algo string) (string, error) {
+	res, err := compute(n, algo)
+	if err != nil {
+		return "", err
+	}
+	return res.Fib.String(), nil
+}
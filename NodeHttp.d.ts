@@ -0,0 +1,8 @@
+import * as http from 'http';
+import Go = require('./Go');
+
+/** A request listener serving requests with the handler registered as name with nodehttp.Handle. */
+export declare function handler(go: Go<any>, name?: string): http.RequestListener;
+
+/** http.createServer(options, handler(go, name)). */
+export declare function createServer(go: Go<any>, name?: string, options?: http.ServerOptions): http.Server;
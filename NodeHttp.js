@@ -0,0 +1,71 @@
+const http = require('http');
+
+// handler returns a request listener for Node's http server that serves
+// requests with the http.Handler the Go program registered as name with
+// nodehttp.Handle. Requests arriving before the program registered it get
+// a 503.
+const handler = (go, name = 'http') => (req, res) => {
+  const serve = go.exports[name];
+  if (typeof serve !== 'function') {
+    res.writeHead(503, { 'Content-Type': 'text/plain; charset=utf-8' });
+    res.end(`no Go handler registered as ${name}\n`);
+    return;
+  }
+
+  const body = req[Symbol.asyncIterator]();
+  // resolves once res is closed or its buffer has room again, to whether
+  // it was closed
+  const drained = () => new Promise((resolve) => {
+    const done = () => {
+      res.off('drain', done);
+      res.off('close', done);
+      resolve(res.destroyed || !res.writable);
+    };
+    res.on('drain', done);
+    res.on('close', done);
+  });
+
+  serve({
+    method: req.method,
+    url: req.url,
+    proto: `HTTP/${req.httpVersion}`,
+    headers: req.rawHeaders,
+    remoteAddr: `${req.socket.remoteAddress}:${req.socket.remotePort}`,
+    closed: new Promise((resolve) => {
+      res.on('close', () => resolve(!res.writableFinished));
+    }),
+    // resolves to the next chunk of the body, or null at its end
+    read: () => body.next().then(({ done, value }) => done ? null : value),
+    trailers: () => req.rawTrailers,
+    writeHead: (status, headers) => {
+      res.writeHead(status, headers);
+    },
+    write: (chunk) => {
+      if (res.destroyed) {
+        return Promise.resolve(true);
+      }
+      return res.write(chunk) ? null : drained();
+    },
+    flush: () => {
+      res.flushHeaders();
+    },
+    end: (trailers) => {
+      const pairs = [];
+      for (let i = 0; i + 1 < trailers.length; i += 2) {
+        pairs.push([trailers[i], trailers[i + 1]]);
+      }
+      if (pairs.length) {
+        res.addTrailers(pairs);
+      }
+      res.end();
+    },
+    destroy: () => {
+      res.destroy();
+    },
+  });
+};
+
+// createServer is http.createServer(options, handler(go, name)).
+const createServer = (go, name = 'http', options = {}) => http.createServer(options, handler(go, name));
+
+module.exports = { handler, createServer };
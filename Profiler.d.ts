@@ -0,0 +1,53 @@
+/**
+ * Samples a Go program with V8's CPU profiler and writes a pprof profile
+ * go tool pprof reads. Locations are per Go function. Node only.
+ */
+declare class Profiler {
+  constructor(go: object, options?: Profiler.Options);
+
+  file?: string;
+  /** Sampling interval in microseconds. */
+  interval: number;
+  /** The profile of the last run, once it stopped. */
+  profile?: Profiler.Profile;
+
+  /** Rejects while another Profiler samples: V8 has one CPU profiler per isolate. */
+  start(): Promise<void>;
+  /** Rejects if this Profiler isn't sampling. */
+  stop(): Promise<Profiler.Profile>;
+  /** The profile as an uncompressed profile.proto message. */
+  encode(): Uint8Array;
+  writeProfile(file?: string): void;
+}
+
+declare namespace Profiler {
+  interface Options {
+    /** File to write the profile to when the program exits. */
+    file?: string;
+    /** Sampling interval in microseconds, 1000 by default. */
+    interval?: number;
+  }
+
+  interface Function {
+    name: string;
+    /** The name V8 reported, for Go functions the name section's. */
+    systemName?: string;
+    file: string;
+    startLine: number;
+    /** Whether it is a Go function rather than JS. */
+    wasm?: boolean;
+  }
+
+  interface Profile {
+    /** Functions by key, which stacks refer to. */
+    funcs: Map<string, Function>;
+    /** Samples by stack, innermost first. */
+    samples: { stack: string[]; count: number }[];
+    /** Nanoseconds a sample stands for. */
+    period: number;
+    timeNanos: number;
+    durationNanos: number;
+  }
+}
+
+export = Profiler;
@@ -0,0 +1,255 @@
+// Profiler samples a Go program running in Go.js with V8's CPU profiler,
+// which Go's runtime/pprof can't do under js/wasm, and writes what it found
+// as a pprof profile, which go tool pprof reads.
+//
+// V8 locates wasm frames by function only, so the profile has a location
+// per Go function, at the line it starts at, and no line of the code
+// within. Stacks end at the Go code JS called, run() or a callback JS
+// resumed Go with; what runs on the JS side of calls from Go, Go.js and
+// the JS it calls, is part of them above the Go frames that called it.
+// Samples without Go frames, JS waiting for Go's timers for one, aren't.
+//
+// V8 has a single CPU profiler per isolate, which stopping one profile
+// stops for all, so only one Profiler samples at a time.
+class Profiler {
+  // interval is the sampling interval in microseconds, file a file to
+  // write the profile to when the program exits
+  constructor(go, { file, interval = 1000 } = {}) {
+    this.go = go;
+    this.file = file;
+    this.interval = interval;
+    this.profile = undefined;
+    this._stop = undefined;
+  }
+
+  // start starts sampling, which needs a platform with a CPU profiler,
+  // Node's inspector, and no other Profiler sampling
+  async start() {
+    if (!this.go.platform.startProfiler) {
+      throw new Error(`CPU profiles need Node's inspector, not available on ${this.go.platform.name}`);
+    }
+    if (active) {
+      throw new Error('another Profiler is sampling, V8 profiles one at a time');
+    }
+    active = this;
+    this.profile = undefined;
+    this._started = Date.now();
+    try {
+      this._stop = await this.go.platform.startProfiler(this.interval);
+    } catch (err) {
+      active = undefined;
+      throw err;
+    }
+  }
+
+  // stop stops sampling and resolves to the profile, as profile.proto's
+  // Profile message with its strings inline. It throws if the Profiler
+  // isn't sampling.
+  async stop() {
+    const stop = this._stop;
+    if (!stop) {
+      throw new Error('not sampling');
+    }
+    this._stop = undefined;
+    try {
+      this.profile = convert(await stop(), this.go.symbols, this.interval, this._started);
+    } finally {
+      // another Profiler may have started since, but not while this one sampled
+      if (active === this) {
+        active = undefined;
+      }
+    }
+    return this.profile;
+  }
+
+  // encode returns the profile encoded as profile.proto, uncompressed
+  encode() {
+    return encode(this.profile, this.go.argv0);
+  }
+
+  writeProfile(file = this.file) {
+    this.go.platform.writeFile(file, this.encode());
+  }
+}
+
+// the Profiler sampling, if any
+let active;
+
+// the runtime's trampolines between JS and Go, and V8's between wasm and
+// JS, which profiles leave out like Symbols' tracebacks
+const trampoline = /^(wasm_|_rt0_wasm|wasm-to-js:|js-to-wasm:)/;
+
+// convert turns a V8 CPU profile into samples of the Go frames and those
+// above them, aggregated by stack. Functions are keyed by their wasm index
+// or, for JS, by name and position.
+const convert = (cpu, symbols, interval, started) => {
+  const nodes = new Map(cpu.nodes.map(node => [node.id, node]));
+  const parents = new Map();
+  cpu.nodes.forEach(node => (node.children || []).forEach(child => parents.set(child, node.id)));
+  const byName = new Map([...symbols.names].map(([index, name]) => [name, index]));
+
+  const funcs = new Map();
+  const frameFunc = ({ functionName, url, lineNumber, columnNumber }) => {
+    if (url.startsWith('wasm://')) {
+      let index = symbols.funcAt(columnNumber);
+      if (index === undefined) {
+        index = byName.get(functionName);
+      }
+      const key = `wasm:${index !== undefined ? index : functionName}`;
+      if (!funcs.has(key)) {
+        const fn = index !== undefined ? symbols.func(index) : {};
+        funcs.set(key, {
+          name: fn.name || functionName || `wasm-function[${index}]`,
+          systemName: functionName,
+          file: fn.file || url,
+          startLine: fn.startLine || 0,
+          wasm: true,
+        });
+      }
+      return key;
+    }
+    const key = `js:${functionName}:${url}:${lineNumber}:${columnNumber}`;
+    if (!funcs.has(key)) {
+      funcs.set(key, { name: functionName || '(anonymous)', file: url, startLine: lineNumber + 1 });
+    }
+    return key;
+  };
+
+  // the stack of each node, innermost first, or null without Go frames
+  const stacks = new Map();
+  const stackOf = (id) => {
+    if (!stacks.has(id)) {
+      const frames = [];
+      for (let n = id; n !== undefined; n = parents.get(n)) {
+        frames.push(nodes.get(n).callFrame);
+      }
+      let outer = frames.length - 1;
+      while (outer >= 0 && !frames[outer].url.startsWith('wasm://')) {
+        outer--;
+      }
+      stacks.set(id, outer < 0 ? null : frames.slice(0, outer + 1)
+        .filter(frame => !trampoline.test(frame.functionName))
+        .map(frameFunc));
+    }
+    return stacks.get(id);
+  };
+
+  const samples = new Map();
+  cpu.samples.forEach((id) => {
+    const stack = stackOf(id);
+    if (!stack || stack.length === 0) {
+      return;
+    }
+    const key = stack.join('\n');
+    const sample = samples.get(key) || { stack, count: 0 };
+    sample.count++;
+    samples.set(key, sample);
+  });
+  return {
+    funcs,
+    samples: [...samples.values()],
+    period: interval * 1000,
+    timeNanos: started * 1e6,
+    durationNanos: (cpu.endTime - cpu.startTime) * 1000,
+  };
+};
+
+// encode writes profile as a Profile message of profile.proto, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+const encode = (profile, file) => {
+  const strings = new Map([['', 0]]);
+  const str = (s) => {
+    if (!strings.has(s)) {
+      strings.set(s, strings.size);
+    }
+    return strings.get(s);
+  };
+  const valueType = (type, unit) => new Message().uint(1, str(type)).uint(2, str(unit));
+
+  const p = new Message();
+  p.message(1, valueType('samples', 'count'));
+  p.message(1, valueType('cpu', 'nanoseconds'));
+  // a location per function, both numbered by the function's place in
+  // funcs from 1
+  const ids = new Map([...profile.funcs.keys()].map((key, i) => [key, i + 1]));
+  profile.samples.forEach(({ stack, count }) => {
+    p.message(2, new Message()
+      .packed(1, stack.map(key => ids.get(key)))
+      .packed(2, [count, count * profile.period]));
+  });
+  p.message(3, new Message().uint(1, 1).uint(5, str(file)).uint(7, 1));
+  [...profile.funcs.values()].forEach((fn, i) => {
+    p.message(4, new Message()
+      .uint(1, i + 1)
+      .uint(2, fn.wasm ? 1 : 0)
+      .message(4, new Message().uint(1, i + 1).uint(2, fn.startLine)));
+  });
+  [...profile.funcs.values()].forEach((fn, i) => {
+    p.message(5, new Message()
+      .uint(1, i + 1)
+      .uint(2, str(fn.name))
+      .uint(3, str(fn.systemName || fn.name))
+      .uint(4, str(fn.file))
+      .uint(5, fn.startLine));
+  });
+  p.uint(9, profile.timeNanos);
+  p.uint(10, profile.durationNanos);
+  p.message(11, valueType('cpu', 'nanoseconds'));
+  p.uint(12, profile.period);
+  // the string table goes last, once everything added its strings
+  const out = new Message();
+  [...strings.keys()].forEach(s => out.string(6, s));
+  return Uint8Array.from([...p.bytes, ...out.bytes]);
+};
+
+// Message encodes the fields of a protocol buffer message, of the types
+// profile.proto uses: non-negative integers, strings, messages and packed
+// integers. Zero integers are left out, as proto3 does.
+class Message {
+  constructor() {
+    this.bytes = [];
+  }
+
+  varint(v) {
+    v = Math.round(v);
+    while (v >= 0x80) {
+      this.bytes.push((v % 0x80) | 0x80);
+      v = Math.floor(v / 0x80);
+    }
+    this.bytes.push(v);
+    return this;
+  }
+
+  uint(field, v) {
+    if (v) {
+      this.varint(field * 8).varint(v);
+    }
+    return this;
+  }
+
+  raw(field, bytes) {
+    this.varint(field * 8 + 2).varint(bytes.length);
+    bytes.forEach(b => this.bytes.push(b));
+    return this;
+  }
+
+  string(field, s) {
+    return this.raw(field, [...new TextEncoder().encode(s)]);
+  }
+
+  message(field, m) {
+    return this.raw(field, m.bytes);
+  }
+
+  packed(field, values) {
+    const m = new Message();
+    values.forEach(v => m.varint(v));
+    return this.raw(field, m.bytes);
+  }
+}
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = Profiler;
+} else {
+  globalThis.GoProfiler = Profiler;
+}
@@ -0,0 +1,4 @@
+/** fs, path and process globals for the Go class that make root the program's /. */
+declare function rootGlobals(root: string): { fs: object; path: object; process: object };
+
+export = rootGlobals;
@@ -0,0 +1,64 @@
+const fs = require('fs');
+const path = require('path');
+
+// fs functions Go's syscall package calls with paths, and which of their
+// arguments are paths. symlink targets are left alone so relative links
+// keep working.
+const pathArgs = {
+  open: [0],
+  stat: [0],
+  lstat: [0],
+  mkdir: [0],
+  readdir: [0],
+  unlink: [0],
+  rmdir: [0],
+  chmod: [0],
+  chown: [0],
+  lchown: [0],
+  utimes: [0],
+  rename: [0, 1],
+  truncate: [0],
+  readlink: [0],
+  symlink: [1],
+  link: [0, 1],
+};
+
+// rootGlobals returns fs, path and process globals for the Go class that
+// make the Go program see root as / and start in it. Paths can't be used to
+// leave root, but symlinks inside it are followed on the host, so this is
+// a convenience rather than a sandbox.
+const rootGlobals = (root) => {
+  root = path.resolve(root);
+  let cwd = '/';
+
+  const toHost = (p) => path.join(root, path.posix.resolve(cwd, String(p)));
+
+  const rootedFs = Object.create(fs);
+  Object.entries(pathArgs).forEach(([name, indexes]) => {
+    rootedFs[name] = (...args) => {
+      indexes.forEach((i) => {
+        args[i] = toHost(args[i]);
+      });
+      return fs[name](...args);
+    };
+  });
+
+  const rootedPath = Object.create(path.posix);
+  rootedPath.resolve = (...paths) => path.posix.resolve(cwd, ...paths);
+
+  const rootedProcess = Object.create(process);
+  rootedProcess.cwd = () => cwd;
+  rootedProcess.chdir = (dir) => {
+    const next = path.posix.resolve(cwd, String(dir));
+    if (!fs.statSync(toHost(next)).isDirectory()) {
+      const err = new Error(`ENOTDIR: not a directory, chdir '${dir}'`);
+      err.code = 'ENOTDIR';
+      throw err;
+    }
+    cwd = next;
+  };
+
+  return { fs: rootedFs, path: rootedPath, process: rootedProcess };
+};
+
+module.exports = rootGlobals;
@@ -0,0 +1,40 @@
+import { ChildProcess, SpawnOptions } from 'child_process';
+import { Readable, Writable } from 'stream';
+import Go = require('./Go');
+
+/** A client for JSON-RPC 2.0 over stdio, as served by Main.go -rpc. */
+declare class RpcClient {
+  constructor(input: Readable, output: Writable);
+  readonly input: Readable;
+  readonly output: Writable;
+  /** Resolves once the program's output ended. */
+  readonly closed: Promise<void>;
+  /** The Go instance of RpcClient.go. */
+  go?: Go<any>;
+  /** The child process of RpcClient.spawn. */
+  process?: ChildProcess;
+  /** What run() resolves to for RpcClient.go, the exit code for RpcClient.spawn. */
+  done?: Promise<unknown>;
+
+  /** Resolves to the result of method, or rejects with an RpcError. */
+  call<T = unknown>(method: string, params?: unknown[] | Record<string, unknown>): Promise<T>;
+  /** Sends method without an id, the program doesn't answer it. */
+  notify(method: string, params?: unknown[] | Record<string, unknown>): void;
+  /** Ends the program's input and resolves once its output ended. */
+  close(): Promise<void>;
+
+  /** Runs a Go.js program with args and connects to its stdio. */
+  static go(source: Go.Source | PromiseLike<Go.Source>, args?: string[], options?: Go.Options): RpcClient & { go: Go<any>; done: Promise<Go.RunResult> };
+  /** Runs command in a child process and connects to its stdio. */
+  static spawn(command: string, args?: string[], options?: SpawnOptions): RpcClient & { process: ChildProcess; done: Promise<number | null> };
+
+  static RpcError: typeof RpcError;
+}
+
+/** What a call rejects with when the program answers with a JSON-RPC error. */
+declare class RpcError extends Error {
+  code: number;
+  data?: unknown;
+}
+
+export = RpcClient;
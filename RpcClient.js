@@ -0,0 +1,145 @@
+const { spawn } = require('child_process');
+const { PassThrough } = require('stream');
+const Go = require('./Go');
+
+// RpcError is what a call rejects with when the program answers with a
+// JSON-RPC error.
+class RpcError extends Error {
+  constructor({ code, message, data }) {
+    super(message);
+    this.name = 'RpcError';
+    this.code = code;
+    this.data = data;
+  }
+}
+
+// RpcClient talks JSON-RPC 2.0 to a program serving it over stdio, like
+// Main.go -rpc: one request per line on the program's stdin, one response
+// per line on its stdout. Responses are matched to calls by id, so any
+// number of calls can be in flight and be answered in any order.
+//
+// input is the stream responses are read from, output the one requests
+// are written to. RpcClient.go and RpcClient.spawn connect them to a
+// program run by Go.js or in a child process.
+class RpcClient {
+  constructor(input, output) {
+    this.input = input;
+    this.output = output;
+    this._nextID = 1;
+    this._pending = new Map();
+    this.closed = new Promise((resolve) => {
+      this._resolveClosed = resolve;
+    });
+
+    let buffered = '';
+    input.setEncoding('utf8');
+    input.on('data', (chunk) => {
+      buffered += chunk;
+      let nl;
+      while ((nl = buffered.indexOf('\n')) !== -1) {
+        const line = buffered.slice(0, nl).trim();
+        buffered = buffered.slice(nl + 1);
+        if (line) {
+          this._receive(line);
+        }
+      }
+    });
+    input.on('end', () => this._close(new Error('connection closed before the response')));
+    input.on('error', err => this._close(err));
+  }
+
+  // call resolves to the result of method, or rejects with an RpcError
+  call(method, params) {
+    const id = this._nextID++;
+    return new Promise((resolve, reject) => {
+      if (this._closeError) {
+        reject(this._closeError);
+        return;
+      }
+      this._pending.set(id, { resolve, reject });
+      this._send({ jsonrpc: '2.0', method, params, id });
+    });
+  }
+
+  // notify sends method without an id, the program doesn't answer it
+  notify(method, params) {
+    this._send({ jsonrpc: '2.0', method, params });
+  }
+
+  // close ends the program's input and resolves once its output ended
+  close() {
+    this.output.end();
+    return this.closed;
+  }
+
+  _send(message) {
+    this.output.write(`${JSON.stringify(message)}\n`);
+  }
+
+  _receive(line) {
+    let responses;
+    try {
+      responses = [].concat(JSON.parse(line));
+    } catch (err) {
+      return;
+    }
+    responses.forEach((res) => {
+      const call = res && this._pending.get(res.id);
+      if (!call) {
+        return;
+      }
+      this._pending.delete(res.id);
+      if (res.error) {
+        call.reject(new RpcError(res.error));
+      } else {
+        call.resolve(res.result);
+      }
+    });
+  }
+
+  _close(err) {
+    if (this._closeError) {
+      return;
+    }
+    this._closeError = err;
+    this._pending.forEach(call => call.reject(err));
+    this._pending.clear();
+    this._resolveClosed();
+  }
+
+  // go runs a Go.js program with args, e.g. main.wasm -rpc, and returns a
+  // client connected to its stdio. client.go is the Go instance and
+  // client.done resolves to what its run() does.
+  static go(source, args = ['-rpc'], options = {}) {
+    const requests = new PassThrough();
+    const responses = new PassThrough();
+    const reader = requests[Symbol.asyncIterator]();
+    const go = new Go(source, Object.assign({}, options, {
+      stdin: () => reader.next().then(({ done, value }) => done ? null : value),
+      stdout: chunk => responses.write(chunk) || new Promise(resolve => responses.once('drain', resolve)),
+    }));
+    const client = new RpcClient(responses, requests);
+    client.go = go;
+    client.done = go.run(...args).finally(() => responses.end());
+    return client;
+  }
+
+  // spawn runs command in a child process, e.g. a native build of Main.go
+  // or go-in-js with a wasm binary, and returns a client connected to its
+  // stdio. client.process is the child and client.done resolves to its
+  // exit code.
+  static spawn(command, args = ['-rpc'], options = {}) {
+    const child = spawn(command, args, Object.assign({ stdio: ['pipe', 'pipe', 'inherit'] }, options));
+    const client = new RpcClient(child.stdout, child.stdin);
+    client.process = child;
+    client.done = new Promise((resolve, reject) => {
+      child.on('error', reject);
+      child.on('exit', code => resolve(code));
+    });
+    return client;
+  }
+}
+
+RpcClient.RpcError = RpcError;
+
+module.exports = RpcClient;
@@ -0,0 +1,41 @@
+/**
+ * Maps the functions of a Go wasm module, and code offsets engines report
+ * in stack traces, to Go functions, files and lines. Files and lines need
+ * the module's bytes.
+ */
+declare class Symbols {
+  constructor(module: WebAssembly.Module, bytes?: BufferSource);
+
+  readonly module: WebAssembly.Module;
+  /** Function names of the module's name section by index. */
+  readonly names: Map<number, string>;
+  readonly importedFuncs: number;
+
+  /** The Go name of the function at index. */
+  name(index: number): string | undefined;
+  /** The Go function at index, with its file and the line it starts at. */
+  func(index: number): { index: number; name?: string; file?: string; startLine?: number };
+  /** The index of the function whose code is at offset in the module, given its bytes. */
+  funcAt(offset: number): number | undefined;
+  frame(index: number, offset?: number): Symbols.Frame;
+  /** The wasm frames of an error's stack, innermost first. */
+  frames(stack: string): Symbols.Frame[];
+  /** The Go frames of an error's stack formatted like a Go traceback. */
+  traceback(stack: string): string;
+}
+
+declare namespace Symbols {
+  interface Frame {
+    /** Function index in the module. */
+    index: number;
+    /** Code offset in the module. */
+    offset?: number;
+    name?: string;
+    /** PC relative to the function's entry, like Go's +0x offsets. */
+    pc?: number;
+    file?: string;
+    line?: number;
+  }
+}
+
+export = Symbols;
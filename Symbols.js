@@ -0,0 +1,465 @@
+// Symbols maps the functions of a Go wasm module, and positions in their
+// code as engines report them in stack traces, to Go functions, files and
+// lines.
+//
+// Function names come from the name section the Go linker writes, unless
+// the binary was built with -ldflags=-s. The linker replaces punctuation
+// in them by underscores, main.(*T).m becomes main.__T_.m. Given the
+// module's bytes too, Symbols reads the runtime's own function table,
+// runtime.pclntab, from the data segments for the names as Go spells them
+// and for files and lines.
+//
+// Go functions keep a resume point after every call and a br_table at
+// their start jumping to the one in PC_B, their first parameter. A Go PC is
+// the function's index << 16 plus the resume point, so a code offset maps
+// to the last PC before the resume point following it, which for callers
+// is the PC of the call itself, like Go reports them.
+class Symbols {
+  // module is a compiled WebAssembly.Module, bytes optionally its source
+  constructor(module, bytes) {
+    this.module = module;
+    this.bytes = bytes && new Uint8Array(bytes.buffer || bytes, bytes.byteOffset || 0, bytes.byteLength);
+    this.names = funcNames(module);
+    this.importedFuncs = WebAssembly.Module.imports(module).filter(imp => imp.kind === 'function').length;
+    this._table = undefined;
+  }
+
+  // name returns the name of the function at index, or undefined
+  name(index) {
+    const fn = this._func(index);
+    return fn ? fn.name : this.names.get(index);
+  }
+
+  // func describes the function at index as { index, name, file,
+  // startLine }, with what is known of these
+  func(index) {
+    const fn = this._func(index);
+    if (!fn) {
+      return { index, name: this.names.get(index) };
+    }
+    const file = pcValue(this.table, fn.pcfile, 0);
+    return { index, name: fn.name, file: file && fileName(this.table, fn, file.value), startLine: fn.startLine };
+  }
+
+  // funcAt returns the index of the function whose code is at offset in
+  // the module, or undefined without the module's bytes. V8's CPU profiles
+  // locate functions by the offset of their body.
+  funcAt(offset) {
+    const bodies = this.table ? this.table.bodies : [];
+    let lo = 0;
+    let hi = bodies.length;
+    while (lo < hi) {
+      const mid = (lo + hi) >>> 1;
+      if (bodies[mid] <= offset) {
+        lo = mid + 1;
+      } else {
+        hi = mid;
+      }
+    }
+    return lo === 0 ? undefined : lo - 1 + this.importedFuncs;
+  }
+
+  // frame resolves the function at index and the code offset in the module
+  // an engine reported for it to { index, offset, name, pc, file, line },
+  // with what is known of these
+  frame(index, offset) {
+    const fn = this._func(index);
+    if (!fn || offset === undefined) {
+      return { index, offset, name: this.name(index) };
+    }
+    const pc = lastPC(this.bytes, this.table.bodies[index - this.importedFuncs], offset);
+    return Object.assign(this._at(index, pc), { offset });
+  }
+
+  // frames resolves the wasm frames of an error's stack, innermost first
+  frames(stack) {
+    const frames = [];
+    const re = /wasm-function\[(\d+)\]:0x([0-9a-f]+)/g;
+    let m;
+    while ((m = re.exec(stack)) !== null) {
+      frames.push(this.frame(Number(m[1]), parseInt(m[2], 16)));
+    }
+    return frames;
+  }
+
+  // goFrames returns the frames of the goroutine an error's stack was
+  // thrown on, innermost first, reading the return addresses Go keeps on
+  // its own stack in memory from sp, Go's stack pointer then. Unlike the
+  // engine's frames they don't end where JS last resumed Go or at
+  // Error.stackTraceLimit. It returns an empty list without the pclntab
+  // or if the innermost engine frame isn't a Go function.
+  goFrames(stack, memory, sp) {
+    const [first] = this.frames(stack);
+    if (!first || first.line === undefined) {
+      return [];
+    }
+    const view = new DataView(memory.buffer || memory);
+    const frames = [first];
+    let frame = first;
+    while (frames.length < maxFrames) {
+      const fn = this._func(frame.index);
+      const size = fn && fn.name !== 'runtime.goexit' && pcValue(this.table, fn.pcsp, frame.pc);
+      if (!size || sp + size.value + 8 > view.byteLength) {
+        break;
+      }
+      // the return address after the frame, PC_F and the PC_B after the
+      // call
+      const ret = view.getUint32(sp + size.value, true);
+      sp += size.value + 8;
+      const index = (ret >>> 16) - funcValueOffset + this.importedFuncs;
+      if (!this._func(index)) {
+        break;
+      }
+      frame = this._at(index, (ret & 0xffff) - 1);
+      frames.push(frame);
+    }
+    return frames;
+  }
+
+  // traceback formats the Go frames of an error's stack like Go prints
+  // goroutine stacks, leaving out the runtime's wasm trampolines. Given
+  // the instance's memory and Go's stack pointer it walks the goroutine's
+  // stack with goFrames, else it has the frames the engine captured.
+  traceback(stack, memory, sp) {
+    let frames = memory ? this.goFrames(stack, memory, sp) : [];
+    if (frames.length === 0) {
+      frames = this.frames(stack);
+    }
+    return frames
+      .filter(f => f.name === undefined || !/^(wasm_|_rt0_wasm)/.test(f.name))
+      .map((f) => {
+        const name = f.name === undefined ? `wasm-function[${f.index}]` : f.name;
+        const at = f.line !== undefined
+          ? `${f.file}:${f.line} +0x${f.pc.toString(16)}`
+          : `wasm-function[${f.index}]:0x${f.offset.toString(16)}`;
+        return `${name}(...)\n\t${at}`;
+      })
+      .join('\n');
+  }
+
+  // the pclntab, read on first use, or null without bytes or if there is
+  // none
+  get table() {
+    if (this._table === undefined) {
+      this._table = this.bytes ? readTable(this.bytes) : null;
+    }
+    return this._table;
+  }
+
+  // _func returns the pclntab entry of the function at index
+  _func(index) {
+    return this.table ? this.table.funcs.get(index - this.importedFuncs) : undefined;
+  }
+
+  // _at resolves pc, relative to the entry of the function at index
+  _at(index, pc) {
+    const fn = this._func(index);
+    const frame = { index, name: fn.name, pc };
+    const at = pcValue(this.table, fn.pcln, pc);
+    if (at) {
+      frame.line = at.value;
+      frame.pc = Math.min(pc, at.end - 1);
+      const file = pcValue(this.table, fn.pcfile, frame.pc);
+      frame.file = file && fileName(this.table, fn, file.value);
+    }
+    return frame;
+  }
+}
+
+// the linker adds funcValueOffset to the index of functions for their
+// PC_F, keeping function values clear of null
+const funcValueOffset = 0x1000;
+
+// maxFrames bounds goFrames like Go bounds its tracebacks
+const maxFrames = 100;
+
+// funcNames reads the function names of module's name section by index
+const funcNames = (module) => {
+  const names = new Map();
+  const [section] = WebAssembly.Module.customSections(module, 'name');
+  if (!section) {
+    return names;
+  }
+  const r = new Reader(new Uint8Array(section));
+  while (r.offset < r.bytes.length) {
+    const id = r.byte();
+    const end = r.u32() + r.offset;
+    if (id === 1) {
+      for (let n = r.u32(); n > 0; n--) {
+        const index = r.u32();
+        names.set(index, r.name());
+      }
+    }
+    r.offset = end;
+  }
+  return names;
+};
+
+class Reader {
+  constructor(bytes, offset = 0) {
+    this.bytes = bytes;
+    this.offset = offset;
+  }
+
+  byte() {
+    return this.bytes[this.offset++];
+  }
+
+  // u32 reads an unsigned LEB128 number, which Go's varints are too
+  u32() {
+    let value = 0;
+    let shift = 0;
+    let b;
+    do {
+      b = this.bytes[this.offset++];
+      value += (b & 0x7f) * 2 ** shift;
+      shift += 7;
+    } while (b & 0x80);
+    return value;
+  }
+
+  skipLEB() {
+    while (this.bytes[this.offset++] & 0x80);
+  }
+
+  name() {
+    const len = this.u32();
+    this.offset += len;
+    return new TextDecoder().decode(this.bytes.subarray(this.offset - len, this.offset));
+  }
+}
+
+// sections returns the payload offsets of the module's sections by id
+const sections = (bytes) => {
+  const found = new Map();
+  const r = new Reader(bytes, 8);
+  while (r.offset < bytes.length) {
+    const id = r.byte();
+    const size = r.u32();
+    if (!found.has(id)) {
+      found.set(id, r.offset);
+    }
+    r.offset += size;
+  }
+  return found;
+};
+
+// pcHeader of runtime.pclntab since Go 1.20: its magic, two zero bytes,
+// minLC 1 and ptrSize 8 as on wasm
+const pcHeader = [0xf1, 0xff, 0xff, 0xff, 0, 0, 1, 8];
+
+// readTable reads runtime.pclntab from the data segments of the module in
+// bytes, returning null if it has none Symbols understands
+const readTable = (bytes) => {
+  const found = sections(bytes);
+  if (!found.has(11) || !found.has(10)) {
+    return null;
+  }
+
+  // the initial memory, up to the end of the last active segment
+  const segments = [];
+  let size = 0;
+  const r = new Reader(bytes, found.get(11));
+  for (let n = r.u32(); n > 0; n--) {
+    const flags = r.u32();
+    if (flags === 1) {
+      r.offset += r.u32();
+      continue;
+    }
+    if (flags === 2) {
+      r.u32();
+    }
+    if (r.byte() !== 0x41) { // i32.const
+      return null;
+    }
+    let offset = r.u32();
+    // i32.const is signed, Go's offsets are small positive ones
+    if (bytes[r.offset - 1] & 0x40) {
+      return null;
+    }
+    r.byte(); // end
+    const len = r.u32();
+    segments.push([offset, r.offset, len]);
+    size = Math.max(size, offset + len);
+    r.offset += len;
+  }
+  const mem = new Uint8Array(size);
+  segments.forEach(([offset, start, len]) => mem.set(bytes.subarray(start, start + len), offset));
+
+  let base = -1;
+  search: for (let i = mem.indexOf(0xf1); i !== -1; i = mem.indexOf(0xf1, i + 1)) {
+    for (let j = 1; j < pcHeader.length; j++) {
+      if (mem[i + j] !== pcHeader[j]) {
+        continue search;
+      }
+    }
+    base = i;
+    break;
+  }
+  if (base === -1) {
+    return null;
+  }
+  const view = new DataView(mem.buffer, base);
+  const uintptr = off => view.getUint32(off, true) + view.getUint32(off + 4, true) * 2 ** 32;
+  const table = {
+    view,
+    nfunc: uintptr(8),
+    funcnametab: uintptr(32),
+    cutab: uintptr(40),
+    filetab: uintptr(48),
+    pctab: uintptr(56),
+    functab: uintptr(64),
+    funcs: new Map(),
+    bodies: [],
+  };
+
+  // functions by their index among the module's own, the table holds
+  // their PC_F on wasm
+  const cstring = (off) => {
+    const start = base + off;
+    return new TextDecoder().decode(mem.subarray(start, mem.indexOf(0, start)));
+  };
+  for (let i = 0; i < table.nfunc; i++) {
+    const entry = view.getUint32(table.functab + i * 8, true);
+    const f = table.functab + view.getUint32(table.functab + i * 8 + 4, true);
+    table.funcs.set(entry - funcValueOffset, {
+      name: cstring(table.funcnametab + view.getInt32(f + 4, true)),
+      pcsp: view.getUint32(f + 16, true),
+      pcfile: view.getUint32(f + 20, true),
+      pcln: view.getUint32(f + 24, true),
+      cuOffset: view.getUint32(f + 32, true),
+      startLine: view.getInt32(f + 36, true),
+    });
+  }
+
+  // offsets of the function bodies in the code section
+  const code = new Reader(bytes, found.get(10));
+  for (let n = code.u32(); n > 0; n--) {
+    const len = code.u32();
+    table.bodies.push(code.offset);
+    code.offset += len;
+  }
+  return table;
+};
+
+// pcValue returns the value of the pc-value table at off for pc, and the
+// end of the range it holds for, the last range's past the function's end
+const pcValue = (table, off, pc) => {
+  const r = new Reader(new Uint8Array(table.view.buffer, table.view.byteOffset + table.pctab + off));
+  let value = -1;
+  let end = 0;
+  let last;
+  for (let first = true; ; first = false) {
+    const delta = r.u32();
+    if (delta === 0 && !first) {
+      return last;
+    }
+    // zig-zag encoded
+    value += delta & 1 ? -((delta + 1) / 2) : delta / 2;
+    end += r.u32();
+    last = { value, end };
+    if (pc < end) {
+      return last;
+    }
+  }
+};
+
+const fileName = (table, fn, fileno) => {
+  const off = table.view.getUint32(table.cutab + (fn.cuOffset + fileno) * 4, true);
+  const mem = new Uint8Array(table.view.buffer, table.view.byteOffset + table.filetab + off);
+  return new TextDecoder().decode(mem.subarray(0, mem.indexOf(0)));
+};
+
+// lastPC returns the PC, relative to the function's entry, of the code at
+// offset in the function whose body starts at body: the last PC of the
+// resume point the code belongs to, or Infinity for the function's last if
+// it has none. The br_table at the start of the function dispatches on
+// PC_B into nested blocks, one per resume point, whose ends start them.
+const lastPC = (bytes, body, offset) => {
+  const r = new Reader(bytes, body);
+  r.u32(); // size
+  for (let n = r.u32(); n > 0; n--) {
+    r.u32();
+    r.byte();
+  }
+  let depth = 0;
+  let prev;
+  let targets;
+  let base; // depth outside the dispatch blocks
+  let open; // dispatch blocks not ended yet
+  while (r.offset < offset) {
+    const op = r.byte();
+    if (op === 0x0e && prev === 0x20 && !targets) { // br_table after local.get 0
+      targets = [];
+      for (let n = r.u32() + 1; n > 0; n--) {
+        targets.push(r.u32());
+      }
+      open = Math.max(...targets) + 1;
+      base = depth - open;
+    } else if (op === 0x02 || op === 0x03 || op === 0x04) { // block, loop, if
+      depth++;
+      skipBlockType(r);
+    } else if (op === 0x0b) { // end
+      if (targets && open > 0 && depth === base + open) {
+        open--;
+      }
+      depth--;
+    } else {
+      skipImmediates(r, op);
+    }
+    prev = op;
+  }
+  if (!targets) {
+    return Infinity;
+  }
+  const point = Math.max(Math.max(...targets) - open, 0);
+  return targets.lastIndexOf(point);
+};
+
+const skipBlockType = (r) => {
+  const c = r.bytes[r.offset];
+  if (c === 0x40 || (c >= 0x6f && c <= 0x7f)) {
+    r.offset++;
+  } else {
+    r.skipLEB();
+  }
+};
+
+// skipImmediates skips the immediates of the instruction op, for the
+// instructions Go emits
+const skipImmediates = (r, op) => {
+  if (op === 0x0c || op === 0x0d || op === 0x10 || (op >= 0x20 && op <= 0x24) || op === 0x41 || op === 0x42) {
+    r.skipLEB(); // br, br_if, call, local and global get and set, consts
+  } else if (op === 0x0e) {
+    for (let n = r.u32() + 1; n > 0; n--) {
+      r.skipLEB();
+    }
+  } else if (op === 0x11 || (op >= 0x28 && op <= 0x3e)) {
+    r.skipLEB(); // call_indirect, loads and stores
+    r.skipLEB();
+  } else if (op === 0x3f || op === 0x40) {
+    r.offset++; // memory.size, memory.grow
+  } else if (op === 0x43) {
+    r.offset += 4;
+  } else if (op === 0x44) {
+    r.offset += 8;
+  } else if (op === 0xfc) {
+    const sub = r.u32();
+    if (sub === 10) {
+      r.offset += 2; // memory.copy
+    } else if (sub === 11) {
+      r.offset++; // memory.fill
+    } else if (sub === 8) {
+      r.skipLEB(); // memory.init
+      r.offset++;
+    } else if (sub === 9) {
+      r.skipLEB(); // data.drop
+    }
+  }
+};
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = Symbols;
+} else {
+  globalThis.GoSymbols = Symbols;
+}
@@ -0,0 +1,40 @@
+/** Records every call a Go program makes into Go.js. */
+declare class Tracer {
+  constructor(go: object, options?: { log?: boolean; file?: string; maxEvents?: number });
+
+  log: boolean;
+  file?: string;
+  /** Events kept without a file, the latest 10000 by default. */
+  maxEvents: number;
+  /** Every call with a file, else at least the latest maxEvents. */
+  events: Tracer.TraceEvent[];
+
+  reset(): void;
+  /** Per-import call counts and cumulative time. */
+  stats(): Record<string, Tracer.ImportStats>;
+  /** The timeline as a Chrome trace, without a file only its latest maxEvents. */
+  chromeTrace(): { traceEvents: Tracer.TraceEvent[]; displayTimeUnit: 'ms' };
+  writeChromeTrace(file?: string): void;
+}
+
+declare namespace Tracer {
+  interface ImportStats {
+    calls: number;
+    /** Milliseconds, including Go code re-entered from the import. */
+    time: number;
+  }
+
+  /** A Chrome trace event of one call into Go.js. */
+  interface TraceEvent {
+    name: string;
+    cat: string;
+    ph: 'X';
+    ts: number;
+    dur: number;
+    pid: number;
+    tid: number;
+    args: Record<string, unknown>;
+  }
+}
+
+export = Tracer;
@@ -0,0 +1,192 @@
+// Layout of each import's frame on the Go stack: [name, type, offset].
+// Results are read after the call, from the stack pointer Go has then.
+const signatures = {
+  'runtime.wasmExit': { args: [['code', 'int32', 8]] },
+  'runtime.wasmWrite': { args: [['fd', 'int64', 8], ['p', 'int64', 16], ['n', 'int32', 24]] },
+  'runtime.resetMemoryDataView': {},
+  'runtime.nanotime1': { results: [['', 'int64', 8]] },
+  'runtime.walltime': { results: [['sec', 'int64', 8], ['nsec', 'int32', 16]] },
+  'runtime.scheduleTimeoutEvent': { args: [['delay', 'int64', 8]], results: [['id', 'int32', 16]] },
+  'runtime.clearTimeoutEvent': { args: [['id', 'int32', 8]] },
+  'runtime.getRandomData': { args: [['r', 'slice', 8]] },
+  'syscall/js.finalizeRef': { args: [['v', 'refid', 8]] },
+  'syscall/js.stringVal': { args: [['value', 'string', 8]], results: [['', 'ref', 24]] },
+  'syscall/js.valueGet': { args: [['v', 'ref', 8], ['p', 'string', 16]], results: [['', 'ref', 32]] },
+  'syscall/js.valueSet': { args: [['v', 'ref', 8], ['p', 'string', 16], ['x', 'ref', 32]] },
+  'syscall/js.valueDelete': { args: [['v', 'ref', 8], ['p', 'string', 16]] },
+  'syscall/js.valueIndex': { args: [['v', 'ref', 8], ['i', 'int64', 16]], results: [['', 'ref', 24]] },
+  'syscall/js.valueSetIndex': { args: [['v', 'ref', 8], ['i', 'int64', 16], ['x', 'ref', 24]] },
+  'syscall/js.valueCall': {
+    args: [['v', 'ref', 8], ['m', 'string', 16], ['args', 'refs', 32]],
+    results: [['', 'ref', 56], ['ok', 'bool', 64]],
+  },
+  'syscall/js.valueInvoke': { args: [['v', 'ref', 8], ['args', 'refs', 16]], results: [['', 'ref', 40], ['ok', 'bool', 48]] },
+  'syscall/js.valueNew': { args: [['v', 'ref', 8], ['args', 'refs', 16]], results: [['', 'ref', 40], ['ok', 'bool', 48]] },
+  'syscall/js.valueLength': { args: [['v', 'ref', 8]], results: [['', 'int64', 16]] },
+  'syscall/js.valuePrepareString': { args: [['v', 'ref', 8]], results: [['', 'ref', 16], ['n', 'int64', 24]] },
+  'syscall/js.valueLoadString': { args: [['v', 'ref', 8], ['b', 'slice', 16]] },
+  'syscall/js.valueInstanceOf': { args: [['v', 'ref', 8], ['t', 'ref', 16]], results: [['', 'bool', 24]] },
+  'syscall/js.copyBytesToGo': { args: [['dst', 'slice', 8], ['src', 'ref', 32]], results: [['n', 'int64', 40], ['ok', 'bool', 48]] },
+  'syscall/js.copyBytesToJS': { args: [['dst', 'ref', 8], ['src', 'slice', 16]], results: [['n', 'int64', 40], ['ok', 'bool', 48]] },
+};
+
+// Tracer records every call the Go program makes into Go.js: decoded
+// arguments and results, per-import counts and time, and a timeline that
+// can be written as a Chrome trace-event file (chrome://tracing, Perfetto).
+// The timeline has every call when there's a file to write it to, else only
+// the latest maxEvents, so long runs don't fill memory.
+class Tracer {
+  constructor(go, { log = false, file, maxEvents = 10000 } = {}) {
+    this.go = go;
+    this.log = log;
+    this.file = file;
+    this.maxEvents = maxEvents;
+    this.reset();
+  }
+
+  reset() {
+    this.origin = this.go.now;
+    this.events = [];
+    this.counts = new Map();
+  }
+
+  call(name, addr, fn) {
+    const go = this.go;
+    const sig = signatures[name] || {};
+    const args = this.decode(sig.args, addr);
+    const start = go.now;
+    let end;
+    let results;
+    let error;
+    try {
+      const ret = fn();
+      end = go.now;
+      // a call that threw left no results on the stack
+      if (sig.results && go.instance) {
+        results = this.decode(sig.results, go.sp);
+      }
+      return ret;
+    } catch (err) {
+      end = go.now;
+      error = err;
+      throw err;
+    } finally {
+      this.record(name, start, end, args, results, error);
+    }
+  }
+
+  record(name, start, end, args, results, error) {
+    const dur = end - start;
+    const stat = this.counts.get(name) || { calls: 0, time: 0 };
+    stat.calls++;
+    stat.time += dur;
+    this.counts.set(name, stat);
+
+    const [cat] = name.split('.');
+    this.events.push({
+      name,
+      cat,
+      ph: 'X',
+      ts: (start - this.origin) * 1000,
+      dur: dur * 1000,
+      pid: 1,
+      tid: 1,
+      args: Object.assign({}, args, results && { result: Object.keys(results).join() === '' ? results[''] : results }, error && { error: String(error) }),
+    });
+    // dropping a maxEvents at a time keeps trimming cheap
+    if (!this.file && this.events.length >= 2 * this.maxEvents) {
+      this.events.splice(0, this.events.length - this.maxEvents);
+    }
+
+    if (this.log) {
+      const fmt = (vals = {}) => Object.entries(vals).map(([k, v]) => k ? `${k}=${v}` : v).join(', ');
+      let ret = results ? ` => ${fmt(results)}` : '';
+      if (error) {
+        ret = ` threw ${error}`;
+      }
+      console.debug(`${name}(${fmt(args)})${ret} [${dur.toFixed(3)}ms]`);
+    }
+  }
+
+  decode(fields = [], addr) {
+    const out = {};
+    fields.forEach(([name, type, offset]) => {
+      out[name] = this.format(type, addr + offset);
+    });
+    return out;
+  }
+
+  format(type, addr) {
+    const go = this.go;
+    switch (type) {
+      case 'int32':
+        return go.getInt32(addr);
+      case 'int64':
+        return go.getInt64(addr);
+      case 'bool':
+        return go.mem.getUint8(addr) !== 0;
+      case 'string':
+        return describe(go.loadString(addr));
+      case 'slice':
+        return `[]byte(len=${go.getInt64(addr + 8)})`;
+      case 'refid':
+        return `ref(${go.getUint32(addr)})`;
+      case 'ref':
+        return go._values ? this.describe(go.loadValue(addr)) : '<exited>';
+      case 'refs':
+        return `[${go.loadSliceOfValues(addr).map(v => this.describe(v)).join(', ')}]`;
+    }
+  }
+
+  describe(v) {
+    if (v === this.go.global) return 'global';
+    if (v === this.go) return 'go';
+    return describe(v);
+  }
+
+  // per-import call counts and cumulative time in ms, including time spent
+  // in Go code re-entered from the import
+  stats() {
+    const stats = {};
+    [...this.counts.keys()].sort().forEach((name) => {
+      stats[name] = Object.assign({}, this.counts.get(name));
+    });
+    return stats;
+  }
+
+  chromeTrace() {
+    const events = this.file ? this.events : this.events.slice(-this.maxEvents);
+    return { traceEvents: events, displayTimeUnit: 'ms' };
+  }
+
+  writeChromeTrace(file = this.file) {
+    this.go.platform.writeFile(file, JSON.stringify(this.chromeTrace()));
+  }
+}
+
+const describe = (v) => {
+  switch (typeof v) {
+    case 'string':
+      return JSON.stringify(v.length > 64 ? `${v.slice(0, 64)}...` : v);
+    case 'function':
+      return `[Function ${v.name || 'anonymous'}]`;
+    case 'symbol':
+      return v.toString();
+    case 'object':
+      if (v === null) return 'null';
+      if (ArrayBuffer.isView(v)) return `${v.constructor.name}(${v.length})`;
+      if (Array.isArray(v)) return `Array(${v.length})`;
+      if (v instanceof Error) return `${v.name}: ${v.message}`;
+      return `[object ${v.constructor ? v.constructor.name : 'Object'}]`;
+    default:
+      return String(v);
+  }
+};
+
+Tracer.signatures = signatures;
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = Tracer;
+} else {
+  globalThis.GoTracer = Tracer;
+}
@@ -0,0 +1,20 @@
+/** The clock of a deterministic Go instance, moved by timers or advance(). */
+declare class VirtualClock {
+  constructor(options?: { time?: number; autoAdvance?: boolean });
+  /** Wall clock at the start in ms since the epoch. */
+  readonly time: number;
+  autoAdvance: boolean;
+  /** ms since the start. */
+  readonly now: number;
+  /** ms since the epoch. */
+  readonly wallTime: number;
+  setTimeout(fn: () => void, delay: number): number;
+  clearTimeout(id: number): void;
+  /** Moves the clock forward by ms, firing the timers due in order. */
+  advance(ms: number): void;
+
+  /** Returns a function filling buffers with random bytes repeatable for seed, not for cryptography. */
+  static seededRandom(seed: number): (buf: Uint8Array) => void;
+}
+
+export = VirtualClock;
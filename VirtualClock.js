@@ -0,0 +1,94 @@
+// VirtualClock is the clock of a deterministic Go instance. Time starts at
+// 0 and only moves when advance() is called or, with autoAdvance, jumps to
+// the next timer once JS is idle, so a sleeping program runs without
+// waiting. time is the wall clock at 0, in ms since the epoch; Go refuses
+// to start on a clock reading 0, so it must be positive.
+class VirtualClock {
+  constructor({ time = Date.UTC(2000, 0, 1), autoAdvance = true } = {}) {
+    this.time = time;
+    this.autoAdvance = autoAdvance;
+    this.now = 0;
+    this._timers = new Map();
+    this._nextID = 1;
+    this._tick = undefined;
+  }
+
+  // ms since the epoch
+  get wallTime() {
+    return this.time + this.now;
+  }
+
+  setTimeout(fn, delay) {
+    const id = this._nextID++;
+    this._timers.set(id, { at: this.now + Math.max(0, delay), fn });
+    this._autoAdvance();
+    return id;
+  }
+
+  clearTimeout(id) {
+    this._timers.delete(id);
+  }
+
+  // moves the clock forward by ms, firing the timers due on the way in order
+  advance(ms) {
+    const until = this.now + ms;
+    let next;
+    while ((next = this._next()) && next.at <= until) {
+      this._fire(next);
+    }
+    this.now = until;
+  }
+
+  // the earliest timer, the first scheduled of those due at the same time
+  _next() {
+    let next;
+    this._timers.forEach((timer, id) => {
+      if (!next || timer.at < next.at) {
+        next = Object.assign({ id }, timer);
+      }
+    });
+    return next;
+  }
+
+  _fire({ id, at, fn }) {
+    this._timers.delete(id);
+    this.now = Math.max(this.now, at);
+    fn();
+  }
+
+  _autoAdvance() {
+    if (!this.autoAdvance || this._tick) {
+      return;
+    }
+    this._tick = setTimeout(() => {
+      this._tick = undefined;
+      const next = this._next();
+      if (next) {
+        this._fire(next);
+        this._autoAdvance();
+      }
+    }, 0);
+  }
+}
+
+// seededRandom returns a function filling buffers with bytes from a
+// mulberry32 generator, repeatable for a seed but not for cryptography.
+const seededRandom = (seed) => {
+  let state = seed >>> 0;
+  return (buf) => {
+    for (let i = 0; i < buf.length; i++) {
+      state = (state + 0x6D2B79F5) | 0;
+      let t = Math.imul(state ^ (state >>> 15), 1 | state);
+      t = (t + Math.imul(t ^ (t >>> 7), 61 | t)) ^ t;
+      buf[i] = (t ^ (t >>> 14)) >>> 24;
+    }
+  };
+};
+
+VirtualClock.seededRandom = seededRandom;
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = VirtualClock;
+} else {
+  globalThis.GoVirtualClock = VirtualClock;
+}
@@ -0,0 +1,13 @@
+/** Reads and changes wasm modules in their binary format. */
+declare namespace WasmBinary {
+  const wasmPageSize: number;
+  /** Reads the unsigned LEB128 number at offset, returning it and the offset after it. */
+  function readLEB(bytes: Uint8Array, offset: number): [number, number];
+  function writeLEB(value: number): number[];
+  /** Returns the module with the maximum of its memory set to maxBytes, in whole pages. */
+  function limitMemory(bytes: Uint8Array, maxBytes: number): Uint8Array;
+  /** Returns the module and name of each import, without compiling the module. */
+  function readImports(bytes: Uint8Array): { module: string; name: string }[];
+}
+
+export = WasmBinary;
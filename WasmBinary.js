@@ -0,0 +1,139 @@
+// WasmBinary reads and changes wasm modules in their binary format, for
+// what Go.js needs of them before compiling: the LEB128 numbers the format
+// is made of, the limit of the memory a Go program defines and what it
+// imports.
+
+const wasmPageSize = 65536;
+
+// readLEB reads the unsigned LEB128 number at offset of bytes, returning it
+// and the offset after it.
+const readLEB = (bytes, offset) => {
+  let value = 0;
+  let shift = 0;
+  let b;
+  do {
+    b = bytes[offset++];
+    value += (b & 0x7f) * 2 ** shift;
+    shift += 7;
+  } while (b & 0x80);
+  return [value, offset];
+};
+
+const writeLEB = (value) => {
+  const out = [];
+  do {
+    let b = value % 128;
+    value = Math.floor(value / 128);
+    if (value) {
+      b |= 0x80;
+    }
+    out.push(b);
+  } while (value);
+  return out;
+};
+
+// limitMemory returns the wasm module in bytes with the maximum of the
+// memory it defines set to maxBytes, rounded down to whole pages, so that
+// memory.grow fails beyond it. Go programs define a single memory.
+const limitMemory = (bytes, maxBytes) => {
+  const maxPages = Math.floor(maxBytes / wasmPageSize);
+  let offset = 8;
+  while (offset < bytes.length) {
+    const id = bytes[offset];
+    const [size, payload] = readLEB(bytes, offset + 1);
+    if (id !== 5) {
+      offset = payload + size;
+      continue;
+    }
+    const [count, limits] = readLEB(bytes, payload);
+    const flags = bytes[limits];
+    if (count !== 1 || flags > 1) {
+      throw new Error('maxMemory needs a module with one 32-bit unshared memory');
+    }
+    const [min, afterMin] = readLEB(bytes, limits + 1);
+    if (min > maxPages) {
+      throw new Error(`maxMemory is ${maxBytes} bytes, but the module starts with ${min * wasmPageSize}`);
+    }
+    let [max, end] = [maxPages, afterMin];
+    if (flags === 1) {
+      [max, end] = readLEB(bytes, afterMin);
+      max = Math.min(max, maxPages);
+    }
+    const entries = [...writeLEB(1), 1, ...writeLEB(min), ...writeLEB(max)];
+    const section = [5, ...writeLEB(entries.length), ...entries];
+    const out = new Uint8Array(offset + section.length + bytes.length - end);
+    out.set(bytes.subarray(0, offset));
+    out.set(section, offset);
+    out.set(bytes.subarray(end), offset + section.length);
+    return out;
+  }
+  throw new Error('maxMemory needs a module defining its memory');
+};
+
+// readImports returns the module and name of each import of the wasm
+// module in bytes, like WebAssembly.Module.imports but without compiling
+// it.
+const readImports = (bytes) => {
+  const decoder = new TextDecoder();
+  let offset = 8;
+  while (offset < bytes.length) {
+    const id = bytes[offset];
+    const [size, payload] = readLEB(bytes, offset + 1);
+    if (id !== 2) {
+      offset = payload + size;
+      continue;
+    }
+    const name = () => {
+      let len;
+      [len, offset] = readLEB(bytes, offset);
+      offset += len;
+      return decoder.decode(bytes.subarray(offset - len, offset));
+    };
+    // the limits of a table or memory: flags, then min and, with bit 0, max
+    const limits = () => {
+      const flags = bytes[offset++];
+      [, offset] = readLEB(bytes, offset);
+      if (flags & 1) {
+        [, offset] = readLEB(bytes, offset);
+      }
+    };
+    const imports = [];
+    let count;
+    [count, offset] = readLEB(bytes, payload);
+    for (; count > 0; count--) {
+      const imp = { module: name(), name: name() };
+      const kind = bytes[offset++];
+      switch (kind) {
+        case 0: // function: its type index
+          [, offset] = readLEB(bytes, offset);
+          break;
+        case 1: // table: its element type and limits
+          offset++;
+          limits();
+          break;
+        case 2: // memory
+          limits();
+          break;
+        case 3: // global: its value type and mutability
+          offset += 2;
+          break;
+        case 4: // tag: its attribute and type index
+          [, offset] = readLEB(bytes, offset + 1);
+          break;
+        default:
+          throw new Error(`unknown import kind ${kind} of ${imp.module}.${imp.name}`);
+      }
+      imports.push(imp);
+    }
+    return imports;
+  }
+  return [];
+};
+
+const WasmBinary = { wasmPageSize, readLEB, writeLEB, limitMemory, readImports };
+
+if (typeof module === 'object' && module.exports) {
+  module.exports = WasmBinary;
+} else {
+  globalThis.GoWasmBinary = WasmBinary;
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// runBatch computes a result for each line read from r and writes it to w
+// in -format. A line holds n, optionally followed by the algorithm to use
+// instead of -algo; blank lines are skipped. Lines that fail are reported
+// to errw with their line number without stopping the batch, runBatch
+// returns how many did.
+//
+// Results are buffered while more input is, and flushed once the batch
+// has to wait for input, so a consumer writing one line at a time gets
+// each result right away and bulk input is answered in bulk.
+func runBatch(r io.Reader, w, errw io.Writer) (failed int, err error) {
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+	for lineNo := 1; ; lineNo++ {
+		if in.Buffered() == 0 {
+			if err := out.Flush(); err != nil {
+				return failed, err
+			}
+		}
+		line, err := in.ReadString('\n')
+		if fields := strings.Fields(line); len(fields) > 0 {
+			res, lineErr := batchLine(fields)
+			if lineErr == nil {
+				lineErr = writeResult(out, res)
+			}
+			if lineErr != nil {
+				failed++
+				fmt.Fprintf(errw, "line %d: %v\n", lineNo, lineErr)
+			}
+		}
+		if err == io.EOF {
+			return failed, nil
+		}
+		if err != nil {
+			return failed, err
+		}
+	}
+}
+
+func batchLine(fields []string) (*result, error) {
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("want n and an optional algorithm, got %q", strings.Join(fields, " "))
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid n %q", fields[0])
+	}
+	name := *algo
+	if len(fields) == 2 {
+		name = fields[1]
+	}
+	return compute(n, name)
+}
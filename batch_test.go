@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	input := "10\n\n  90 iterative \n-1\nx\n93 matrix\n100 big\n5 memo extra\n7 guess\n20"
+	var out, errs bytes.Buffer
+	failed, err := runBatch(strings.NewReader(input), &out, &errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 5 {
+		t.Errorf("failed = %d, want 5", failed)
+	}
+	wantOut := "fib(10) = 55\nfib(90) = 2880067194370816120\nfib(100) = 354224848179261915075\nfib(20) = 6765\n"
+	if out.String() != wantOut {
+		t.Errorf("output = %q\nwant %q", out.String(), wantOut)
+	}
+	wantErrs := `line 4: n must not be negative, got -1
+line 5: invalid n "x"
+line 6: fib(93): result overflows int64, use -algo big
+line 8: want n and an optional algorithm, got "5 memo extra"
+line 9: unknown algorithm "guess"
+`
+	if errs.String() != wantErrs {
+		t.Errorf("errors = %q\nwant %q", errs.String(), wantErrs)
+	}
+}
+
+// TestRunBatchFlushes checks that a result is written before the next line
+// of input arrives.
+func TestRunBatchFlushes(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := runBatch(inR, outW, io.Discard)
+		outW.Close()
+		done <- err
+	}()
+
+	results := bufio.NewReader(outR)
+	for _, tc := range []struct{ line, want string }{
+		{"3 iterative\n", "fib(3) = 2\n"},
+		{"4 iterative\n", "fib(4) = 3\n"},
+	} {
+		if _, err := io.WriteString(inW, tc.line); err != nil {
+			t.Fatal(err)
+		}
+		got, err := results.ReadString('\n')
+		if err != nil || got != tc.want {
+			t.Fatalf("result = %q, %v, want %q", got, err, tc.want)
+		}
+	}
+	inW.Close()
+	if _, err := results.ReadString('\n'); err != io.EOF {
+		t.Errorf("after the input ended: %v, want EOF", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
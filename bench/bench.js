@@ -0,0 +1,117 @@
+// Compares fib(n) computed natively by Go, by Go compiled to wasm and run
+// through Go.js, and by plain JS, and writes the table to bench_output.txt.
+//
+//   npm run build:go && npm run bench -- [n]
+//
+// cold:   a fresh Go instance running the program once, startup included
+// warm:   calling the exported fib of one running instance
+// pooled: calling the exported fib round-robin over a pool of instances
+const { execFileSync } = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const Go = require('../Go');
+const fibJS = require('./fib');
+
+const root = path.join(__dirname, '..');
+const wasm = path.join(root, 'main.wasm');
+const n = Number(process.argv[2]) || 25;
+const algorithms = ['recursive', 'iterative', 'memo', 'matrix', 'big'];
+const coldRuns = 5;
+const poolSize = 4;
+const budget = 500; // ms per measurement
+
+const now = () => {
+  const [sec, nsec] = process.hrtime();
+  return sec * 1e9 + nsec;
+};
+
+// ns per call of fn, calling it until budget ms have passed
+const measure = (fn) => {
+  for (let i = 0; i < 10; i++) fn();
+  let ops = 0;
+  const start = now();
+  let elapsed = 0;
+  while (elapsed < budget * 1e6) {
+    fn();
+    ops++;
+    elapsed = now() - start;
+  }
+  return elapsed / ops;
+};
+
+// ns/op per algorithm from `go test -bench`, or {} without a Go toolchain
+const native = () => {
+  let out;
+  try {
+    out = execFileSync('go', ['test', '-run', '^$', '-bench', 'BenchmarkAlgorithms', `-fib.n=${n}`, '.'], { cwd: root, encoding: 'utf8' });
+  } catch (err) {
+    console.warn(`skipping native benchmarks: ${err.message}`);
+    return {};
+  }
+  const results = {};
+  const re = /^BenchmarkAlgorithms\/(\w+)(?:-\d+)?\s+\d+\s+([\d.]+) ns\/op/gm;
+  let match;
+  while ((match = re.exec(out))) {
+    results[match[1]] = Number(match[2]);
+  }
+  return results;
+};
+
+const start = async () => {
+  const go = new Go(wasm);
+  go.run('-serve');
+  await go.waitReady();
+  return go;
+};
+
+const cold = async (algo) => {
+  let total = 0;
+  for (let i = 0; i < coldRuns; i++) {
+    const begin = now();
+    const go = new Go(wasm, { capture: true });
+    const { code, stderr } = await go.run('-algo', algo, n);
+    if (code !== 0) {
+      throw new Error(`${algo}: exit code ${code}: ${stderr}`);
+    }
+    total += now() - begin;
+  }
+  return total / coldRuns;
+};
+
+const format = (ns) => {
+  if (ns === undefined) return '-';
+  if (ns >= 1e6) return `${(ns / 1e6).toFixed(2)} ms`;
+  if (ns >= 1e3) return `${(ns / 1e3).toFixed(2)} µs`;
+  return `${ns.toFixed(1)} ns`;
+};
+
+const table = (rows) => {
+  const widths = rows[0].map((_, i) => Math.max(...rows.map(row => row[i].length)));
+  return rows.map(row => row.map((cell, i) => cell.padEnd(widths[i])).join('  ').trimEnd()).join('\n');
+};
+
+(async () => {
+  const nativeResults = native();
+  const warmGo = await start();
+  const pool = await Promise.all(Array.from({ length: poolSize }, start));
+
+  const rows = [['algorithm', 'native', 'wasm cold', 'wasm warm', 'wasm pooled', 'js']];
+  for (const algo of algorithms) {
+    let next = 0;
+    rows.push([
+      algo,
+      format(nativeResults[algo]),
+      format(await cold(algo)),
+      format(measure(() => warmGo.exports.fib(n, algo))),
+      format(measure(() => pool[next++ % poolSize].exports.fib(n, algo))),
+      format(measure(() => fibJS[algo](n))),
+    ]);
+  }
+
+  const report = `fib(${n}), per call\n\n${table(rows)}\n`;
+  fs.writeFileSync(path.join(root, 'bench_output.txt'), report);
+  process.stdout.write(report);
+})().catch((err) => {
+  console.error(err);
+  process.exitCode = 1;
+});
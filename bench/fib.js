@@ -0,0 +1,47 @@
+// The fib algorithms of fib.go in plain JS, as the baseline for bench.js.
+
+const recursive = (n) => n < 2 ? n : recursive(n - 1) + recursive(n - 2);
+
+const iterative = (n) => {
+  let a = 0;
+  let b = 1;
+  for (let i = 0; i < n; i++) {
+    [a, b] = [b, a + b];
+  }
+  return a;
+};
+
+const memo = (n) => {
+  const cache = new Map();
+  const f = (n) => {
+    if (n < 2) return n;
+    if (cache.has(n)) return cache.get(n);
+    const v = f(n - 1) + f(n - 2);
+    cache.set(n, v);
+    return v;
+  };
+  return f(n);
+};
+
+const matrix = (n) => {
+  let [a, b, c, d] = [1, 0, 0, 1];
+  let [x, y, z, w] = [1, 1, 1, 0];
+  for (; n > 0; n = Math.floor(n / 2)) {
+    if (n % 2 === 1) {
+      [a, b, c, d] = [a * x + b * z, a * y + b * w, c * x + d * z, c * y + d * w];
+    }
+    [x, y, z, w] = [x * x + y * z, x * y + y * w, z * x + w * z, z * y + w * w];
+  }
+  return b;
+};
+
+const big = (n) => {
+  let a = 0n;
+  let b = 1n;
+  for (let i = 0; i < n; i++) {
+    [a, b] = [b, a + b];
+  }
+  return a;
+};
+
+module.exports = { recursive, iterative, memo, matrix, big };
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// glueFile is the file gojsbuild writes into the target package to
+// register its exported functions with the host.
+const glueFile = "gojs_exports_js.go"
+
+// interopPath is the import path of the package exports are registered with.
+const interopPath = "go-to-js/interop"
+
+// config is what the templates are executed with.
+type config struct {
+	Package string
+	Name    string   // base name of the wasm, JS and .d.ts files
+	Host    string   // module the JS wrapper requires Go from
+	Args    []string // default arguments the JS wrapper runs the program with
+	Exports []export
+}
+
+// Typed are the exports gojsbuild registers itself.
+func (c config) Typed() []export {
+	var typed []export
+	for _, e := range c.Exports {
+		if !e.Untyped {
+			typed = append(typed, e)
+		}
+	}
+	return typed
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"fromJS": func(t goType, expr string) string {
+		return fmt.Sprintf(t.FromJS, expr)
+	},
+	"comment": func(indent, doc string) string {
+		if doc == "" {
+			return ""
+		}
+		lines := strings.Split(doc, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(indent+" * "+line, " ")
+		}
+		return indent + "/**\n" + strings.Join(lines, "\n") + "\n" + indent + " */\n"
+	},
+}
+
+// glueTmpl prefixes its locals with gojs, so that they don't clash with
+// the exported functions' parameter names, which JS sees.
+var glueTmpl = template.Must(template.New("glue").Funcs(funcs).Parse(`// Code generated by gojsbuild. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"syscall/js"
+
+	{{printf "%q" .Interop}}
+)
+
+func init() {
+{{- range $e := .Typed}}
+	interop.Export({{printf "%q" .JSName}}, func(gojsThis js.Value, gojsArgs []js.Value) any {
+		{{- with .Rest}}
+		// trailing undefined arguments count as left out, as in JS
+		for len(gojsArgs) > {{$e.Required}} && gojsArgs[len(gojsArgs)-1].IsUndefined() {
+			gojsArgs = gojsArgs[:len(gojsArgs)-1]
+		}
+		{{- end}}
+		if len(gojsArgs) < {{.Required}} {
+			{{- if .Variadic}}
+			panic({{printf "%q" (printf "%s: want at least %d arguments" .JSName .Required)}})
+			{{- else}}
+			panic({{printf "%q" (printf "%s: want %d arguments" .JSName .Required)}})
+			{{- end}}
+		}
+		{{- with .Rest}}
+		gojsRest := make([]{{.Type.Name}}, len(gojsArgs)-{{$e.Required}})
+		for gojsI := range gojsRest {
+			gojsRest[gojsI] = {{fromJS .Type (printf "gojsArgs[%d+gojsI]" $e.Required)}}
+		}
+		{{- end}}
+		{{if .Result}}gojsR{{if .Error}}, gojsErr{{end}} := {{else if .Error}}gojsErr := {{end}}{{.GoName}}(
+			{{- range $i, $p := .Params}}{{if $i}}, {{end}}{{if eq $i $e.Required}}gojsRest...{{else}}{{fromJS $p.Type (printf "gojsArgs[%d]" $i)}}{{end}}{{end}})
+		{{- if .Error}}
+		if gojsErr != nil {
+			panic(gojsErr)
+		}
+		{{- end}}
+		return {{if .Result}}gojsR{{else}}nil{{end}}
+	})
+{{- end}}
+}
+`))
+
+var jsTmpl = template.Must(template.New("js").Funcs(funcs).Parse(`// Code generated by gojsbuild. DO NOT EDIT.
+const path = require('path');
+const Go = require({{json .Host}});
+
+const wasm = path.join(__dirname, {{json (printf "%s.wasm" .Name)}});
+const defaultArgs = {{json .Args}};
+
+// load starts the Go program and resolves to its exported functions once it
+// has registered them. Other options are passed to the Go constructor.
+async function load({ args = defaultArgs, ...options } = {}) {
+  const go = new Go(wasm, options);
+  const done = go.run(...args);
+  await go.waitReady();
+  if (go.exited) {
+    const { code } = await done;
+    throw new Error(` + "`" + `Go program exited with code ${code} before its functions could be called` + "`" + `);
+  }
+  return {
+    go,
+    done,
+{{- range .Exports}}
+{{- if .Untyped}}
+    {{.JSName}}: (...args) => go.exports.{{.JSName}}(...args),
+{{- else}}
+    {{.JSName}}: ({{template "params" .}}) => go.exports.{{.JSName}}({{template "params" .}}),
+{{- end}}
+{{- end}}
+  };
+}
+
+module.exports = { load };
+{{- define "params"}}
+{{- range $i, $p := .Params}}{{if $i}}, {{end}}{{if eq $i $.Required}}...{{end}}{{$p.Name}}{{end}}
+{{- end}}
+`))
+
+var dtsTmpl = template.Must(template.New("dts").Funcs(funcs).Parse(`// Code generated by gojsbuild. DO NOT EDIT.
+import Go = require({{json .Host}});
+
+/** The functions the Go program registers on go.exports. */
+export interface GoExports {
+{{- range $i, $e := .Exports}}
+{{- if $i}}
+{{end}}
+{{comment "  " .Doc}}  {{template "signature" .}};
+{{- end}}
+}
+
+export interface Exports extends GoExports {
+  /** The Go instance running the program. */
+  go: Go<GoExports>;
+  /** Settles when the Go program exits. */
+  done: Promise<Go.RunResult>;
+}
+
+export interface LoadOptions extends Go.Options {
+  /** Arguments to run the program with, {{json .Args}} by default. */
+  args?: string[];
+}
+
+/** Starts the Go program and resolves to its exported functions. */
+export function load(options?: LoadOptions): Promise<Exports>;
+{{- define "signature"}}
+{{- if .Untyped}}{{.JSName}}(...args: any[]): any
+{{- else}}{{.JSName}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{if eq $i $.Required}}...{{$p.Name}}: {{$p.Type.TS}}[]{{else}}{{$p.Name}}: {{$p.Type.TS}}{{end}}{{end}}): {{if .Result}}{{.Result.TS}}{{else}}void{{end}}
+{{- end}}
+{{- end}}
+`))
+
+func execute(tmpl *template.Template, c config) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Interop is the import path of the interop package for glue code.
+func (config) Interop() string {
+	return interopPath
+}
+
+func glue(c config) ([]byte, error) {
+	src, err := execute(glueTmpl, c)
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(src)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestDeclarationsCompile runs tsc over the declarations gojsbuild writes
+// for Main.go, the handwritten ones of the JS modules and a file using
+// both. It needs tsc, on the PATH or in node_modules, and @types/node in
+// node_modules, and is skipped without them.
+func TestDeclarationsCompile(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsc, err := exec.LookPath("tsc")
+	if err != nil {
+		tsc = filepath.Join(root, "node_modules", ".bin", "tsc")
+		if _, err := os.Stat(tsc); err != nil {
+			t.Skip("tsc not found, npm install typescript to run")
+		}
+	}
+	typeRoots := filepath.Join(root, "node_modules", "@types")
+	if _, err := os.Stat(filepath.Join(typeRoots, "node")); err != nil {
+		t.Skip("Node's types not found, npm install @types/node to run")
+	}
+
+	_, exports, err := scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := filepath.ToSlash(filepath.Join(root, "Go"))
+	dts, err := execute(dtsTmpl, config{Package: "main", Name: "main", Host: host, Args: []string{"-serve"}, Exports: exports})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.d.ts"), dts, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	use := fmt.Sprintf(`import Go = require(%[1]q);
+import { load } from './main';
+
+export async function use(): Promise<number> {
+  const { go, done, fib, fibString } = await load({ capture: true, deterministic: { seed: 1 } });
+  fib(10);
+  fib(100, 'big');
+  const s: string = fibString(100, 'big');
+  go.exports.fibString(1, 'memo').toUpperCase();
+  go.on('progress', (p: unknown) => p);
+  const { code, stats }: Go.RunResult = await done;
+  return code + stats.wasmTime + s.length;
+}
+`, host)
+	if err := os.WriteFile(filepath.Join(dir, "use.ts"), []byte(use), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	handwritten, err := filepath.Glob(filepath.Join(root, "*.d.ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := append([]string{
+		"--noEmit", "--strict", "--target", "es2020", "--lib", "es2020,dom", "--module", "commonjs",
+		"--typeRoots", typeRoots, "--types", "node",
+		filepath.Join(dir, "main.d.ts"), filepath.Join(dir, "use.ts"),
+	}, handwritten...)
+	cmd := exec.Command(tsc, args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("tsc: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,146 @@
+// Command gojsbuild builds a Go package to js/wasm together with a JS
+// module and TypeScript declarations for calling its exported functions
+// through Go.js.
+//
+// Functions are exported by annotating them, optionally naming them in JS:
+//
+//	// fibString computes fib(n) with the named algorithm.
+//	//
+//	//gojs:export
+//	func fibString(n int, algo string) (string, error)
+//
+// Parameters and results may be ints, uints, floats, strings, bools and
+// js.Value, with at most one result besides a trailing error, which is
+// thrown into JS. A variadic last parameter makes its arguments optional.
+// gojsbuild writes gojs_exports_js.go into the package to register the
+// functions, builds <name>.wasm and writes <name>.js and <name>.d.ts next
+// to it.
+//
+// Usage:
+//
+//	gojsbuild [-o dir] [-name main] [-host module] [-args args] [package dir]
+//
+// Functions the package registers itself with interop.Export are typed
+// loosely, as (...args: any[]) => any.
+//
+// int, int64, uint and uint64 arguments beyond Number.MAX_SAFE_INTEGER,
+// which JS may have rounded, and parameters named after JS reserved words,
+// which the wrapper can't declare, are refused.
+//
+// With -generate only gojs_exports_js.go is written, for use with go
+// generate, and with -types only <name>.d.ts.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var (
+	outDir   = flag.String("o", ".", "output directory")
+	name     = flag.String("name", "main", "base name of the output files")
+	host     = flag.String("host", "go-js/Go", "module the JS wrapper loads the Go class from")
+	args     = flag.String("args", "", "space separated arguments the JS wrapper runs the program with by default")
+	generate = flag.Bool("generate", false, "only write "+glueFile)
+	types    = flag.Bool("types", false, "only write the TypeScript declarations")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gojsbuild [flags] [package dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "gojsbuild: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	pkgName, exports, err := scan(dir)
+	if err != nil {
+		return err
+	}
+	if pkgName != "main" {
+		return fmt.Errorf("%s: package %s is not a command", dir, pkgName)
+	}
+	c := config{
+		Package: pkgName,
+		Name:    *name,
+		Host:    *host,
+		Args:    strings.Fields(*args),
+		Exports: exports,
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+	if *types {
+		return writeTemplate(dtsTmpl, c, ".d.ts")
+	}
+
+	if err := writeGlue(dir, c); err != nil {
+		return err
+	}
+	if *generate {
+		return nil
+	}
+
+	out, err := filepath.Abs(filepath.Join(*outDir, *name+".wasm"))
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build: %v", err)
+	}
+
+	if err := writeTemplate(jsTmpl, c, ".js"); err != nil {
+		return err
+	}
+	return writeTemplate(dtsTmpl, c, ".d.ts")
+}
+
+// writeTemplate writes the output of tmpl to <name><ext> in the output
+// directory.
+func writeTemplate(tmpl *template.Template, c config, ext string) error {
+	b, err := execute(tmpl, c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(*outDir, *name+ext), b, 0o644)
+}
+
+// writeGlue writes the registration code for exports into dir, removing a
+// stale file if there is nothing to export.
+func writeGlue(dir string, c config) error {
+	path := filepath.Join(dir, glueFile)
+	if len(c.Typed()) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := glue(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
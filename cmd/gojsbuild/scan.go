@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// directive marks a function for export, optionally followed by the name
+// it gets in JS.
+const directive = "//gojs:export"
+
+// export is a function of the target package annotated with directive, or
+// one it registers itself by calling interop.Export, which is Untyped as
+// its Go signature is that of every js.Func.
+type export struct {
+	GoName   string
+	JSName   string
+	Doc      string
+	Params   []param
+	Variadic bool    // whether the last parameter is variadic
+	Result   *goType // nil if the function returns nothing but an error
+	Error    bool    // whether the last result is an error
+	Untyped  bool
+}
+
+// Required is the number of arguments JS must pass.
+func (e export) Required() int {
+	if e.Variadic {
+		return len(e.Params) - 1
+	}
+	return len(e.Params)
+}
+
+// Rest is the variadic last parameter, or nil.
+func (e export) Rest() *param {
+	if !e.Variadic {
+		return nil
+	}
+	return &e.Params[len(e.Params)-1]
+}
+
+type param struct {
+	Name string
+	Type goType
+}
+
+// goType is a Go type export signatures may use.
+type goType struct {
+	Name string // as written in Go, e.g. int or js.Value
+	// FromJS converts a js.Value expression, given as %s, to the type.
+	FromJS string
+	TS     string
+}
+
+// basicTypes are the types of basic Go values. The 64-bit integers, which
+// int and uint are under js/wasm, go through interop so that numbers JS
+// can't hold exactly throw instead of arriving rounded.
+var basicTypes = map[string]goType{
+	"int":     {"int", "int(interop.Int64(%s))", "number"},
+	"int8":    {"int8", "int8(%s.Int())", "number"},
+	"int16":   {"int16", "int16(%s.Int())", "number"},
+	"int32":   {"int32", "int32(%s.Int())", "number"},
+	"int64":   {"int64", "interop.Int64(%s)", "number"},
+	"uint":    {"uint", "uint(interop.Uint64(%s))", "number"},
+	"uint8":   {"uint8", "uint8(%s.Int())", "number"},
+	"uint16":  {"uint16", "uint16(%s.Int())", "number"},
+	"uint32":  {"uint32", "uint32(%s.Int())", "number"},
+	"uint64":  {"uint64", "interop.Uint64(%s)", "number"},
+	"float32": {"float32", "float32(%s.Float())", "number"},
+	"float64": {"float64", "%s.Float()", "number"},
+	"string":  {"string", "%s.String()", "string"},
+	"bool":    {"bool", "%s.Bool()", "boolean"},
+}
+
+var jsValue = goType{"js.Value", "%s", "any"}
+
+// jsReserved are the words that are valid Go identifiers but can't name
+// the parameters of the JS wrapper.
+var jsReserved = map[string]bool{
+	"arguments": true, "await": true, "catch": true, "class": true,
+	"debugger": true, "delete": true, "do": true, "enum": true,
+	"eval": true, "export": true, "extends": true, "false": true,
+	"finally": true, "function": true, "implements": true, "in": true,
+	"instanceof": true, "let": true, "new": true, "null": true,
+	"private": true, "protected": true, "public": true, "static": true,
+	"super": true, "this": true, "throw": true, "true": true, "try": true,
+	"typeof": true, "void": true, "while": true, "with": true, "yield": true,
+}
+
+// scan finds the exported functions of the package in dir as it is built
+// for js/wasm.
+func scan(dir string) (pkgName string, exports []export, err error) {
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = "js", "wasm"
+	pkg, err := ctx.ImportDir(dir, 0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+	seen := map[string]string{}
+	var files []*ast.File
+	for _, name := range pkg.GoFiles {
+		if name == glueFile {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return "", nil, err
+		}
+		files = append(files, f)
+	}
+
+	for _, f := range files {
+		jsName := importName(f, "syscall/js")
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			name, ok := exportName(fn)
+			if !ok {
+				continue
+			}
+			pos := fset.Position(fn.Pos())
+			if fn.Recv != nil || fn.Type.TypeParams != nil {
+				return "", nil, fmt.Errorf("%s: %s: only plain functions can be exported", pos, fn.Name.Name)
+			}
+			e, err := newExport(fn, name, jsName)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %s: %v", pos, fn.Name.Name, err)
+			}
+			if prev, dup := seen[name]; dup {
+				return "", nil, fmt.Errorf("%s: %s: %s is already exported by %s", pos, fn.Name.Name, name, prev)
+			}
+			seen[name] = fn.Name.Name
+			exports = append(exports, e)
+		}
+	}
+
+	for _, e := range registered(files) {
+		if prev, dup := seen[e.JSName]; dup {
+			return "", nil, fmt.Errorf("%s is exported by both %s and interop.Export", e.JSName, prev)
+		}
+		seen[e.JSName] = "interop.Export"
+		exports = append(exports, e)
+	}
+	return pkg.Name, exports, nil
+}
+
+// registered finds the functions files register with interop.Export under
+// a constant name, documented by the doc comment of the function passed.
+func registered(files []*ast.File) []export {
+	funcs := map[string]*ast.FuncDecl{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				funcs[fn.Name.Name] = fn
+			}
+		}
+	}
+
+	var exports []export
+	for _, f := range files {
+		interop := importName(f, interopPath)
+		if interop == "" {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 2 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Export" {
+				return true
+			}
+			if x, ok := sel.X.(*ast.Ident); !ok || x.Name != interop {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			name, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			e := export{JSName: name, Untyped: true}
+			if id, ok := call.Args[1].(*ast.Ident); ok && funcs[id.Name] != nil && funcs[id.Name].Doc != nil {
+				e.GoName = id.Name
+				e.Doc = strings.TrimSpace(funcs[id.Name].Doc.Text())
+			}
+			exports = append(exports, e)
+			return true
+		})
+	}
+	return exports
+}
+
+// exportName reports whether fn carries the directive and the JS name.
+func exportName(fn *ast.FuncDecl) (string, bool) {
+	for _, c := range fn.Doc.List {
+		rest, ok := strings.CutPrefix(c.Text, directive)
+		if !ok || (rest != "" && rest[0] != ' ') {
+			continue
+		}
+		if name := strings.TrimSpace(rest); name != "" {
+			return name, true
+		}
+		return lowerFirst(fn.Name.Name), true
+	}
+	return "", false
+}
+
+func newExport(fn *ast.FuncDecl, name, jsName string) (export, error) {
+	e := export{
+		GoName: fn.Name.Name,
+		JSName: name,
+		Doc:    strings.TrimSpace(fn.Doc.Text()),
+	}
+	for i, field := range fn.Type.Params.List {
+		typ := field.Type
+		if ell, ok := typ.(*ast.Ellipsis); ok {
+			typ = ell.Elt
+			e.Variadic = true
+		}
+		t, err := resolve(typ, jsName)
+		if err != nil {
+			return e, err
+		}
+		if len(field.Names) == 0 {
+			e.Params = append(e.Params, param{fmt.Sprintf("arg%d", i), t})
+		}
+		for _, n := range field.Names {
+			if jsReserved[n.Name] {
+				return e, fmt.Errorf("parameter %s is a reserved word in JS, rename it", n.Name)
+			}
+			e.Params = append(e.Params, param{n.Name, t})
+		}
+	}
+
+	var results []ast.Expr
+	if fn.Type.Results != nil {
+		for _, field := range fn.Type.Results.List {
+			for i := 0; i < max(1, len(field.Names)); i++ {
+				results = append(results, field.Type)
+			}
+		}
+	}
+	if n := len(results); n > 0 {
+		if id, ok := results[n-1].(*ast.Ident); ok && id.Name == "error" {
+			e.Error = true
+			results = results[:n-1]
+		}
+	}
+	switch len(results) {
+	case 0:
+	case 1:
+		t, err := resolve(results[0], jsName)
+		if err != nil {
+			return e, err
+		}
+		e.Result = &t
+	default:
+		return e, fmt.Errorf("can return at most one value and an error")
+	}
+	return e, nil
+}
+
+func resolve(expr ast.Expr, jsName string) (goType, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if bt, ok := basicTypes[t.Name]; ok {
+			return bt, nil
+		}
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && x.Name == jsName && t.Sel.Name == "Value" {
+			return jsValue, nil
+		}
+	}
+	return goType{}, fmt.Errorf("unsupported type %s", typeString(expr))
+}
+
+// typeString formats a type expression for error messages.
+func typeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.ArrayType:
+		return "[]" + typeString(t.Elt)
+	case *ast.Ellipsis:
+		return "..." + typeString(t.Elt)
+	case *ast.MapType:
+		return "map[" + typeString(t.Key) + "]" + typeString(t.Value)
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+// importName is the name path is imported as in f, or "" if it isn't.
+func importName(f *ast.File, path string) string {
+	for _, imp := range f.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p != path {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
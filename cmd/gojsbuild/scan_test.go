@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// writePackage writes src as main.go of a new package directory.
+func writePackage(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	dir := writePackage(t, `package main
+
+import jsv "syscall/js"
+
+// Add adds.
+//
+//gojs:export
+func Add(a, b int) int { return a + b }
+
+//gojs:export greet
+func hello(name string, v jsv.Value) (string, error) { return "", nil }
+
+//gojs:export
+func reset() error { return nil }
+
+//gojs:export
+func sum(first int, rest ...int) int { return 0 }
+
+//gojs:exported is not the directive
+func notExported() {}
+
+func main() {}
+`)
+	pkg, exports, err := scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != "main" {
+		t.Errorf("package = %q, want main", pkg)
+	}
+	if len(exports) != 4 {
+		t.Fatalf("got %d exports, want 4: %+v", len(exports), exports)
+	}
+
+	add, greet, reset, sum := exports[0], exports[1], exports[2], exports[3]
+	if add.JSName != "add" || add.Doc != "Add adds." || len(add.Params) != 2 || add.Result.TS != "number" || add.Error {
+		t.Errorf("Add = %+v", add)
+	}
+	if greet.JSName != "greet" || greet.GoName != "hello" || greet.Params[1].Type != jsValue || !greet.Error {
+		t.Errorf("hello = %+v", greet)
+	}
+	if reset.Result != nil || !reset.Error || len(reset.Params) != 0 {
+		t.Errorf("reset = %+v", reset)
+	}
+	if !sum.Variadic || sum.Required() != 1 || sum.Rest().Name != "rest" || add.Variadic || add.Rest() != nil {
+		t.Errorf("sum = %+v", sum)
+	}
+
+	src, err := glue(config{Package: pkg, Exports: exports})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`gojsR := Add(int(interop.Int64(gojsArgs[0])), int(interop.Int64(gojsArgs[1])))`,
+		`gojsR, gojsErr := hello(gojsArgs[0].String(), gojsArgs[1])`,
+		`gojsErr := reset()`,
+		`gojsRest[gojsI] = int(interop.Int64(gojsArgs[1+gojsI]))`,
+		`gojsR := sum(int(interop.Int64(gojsArgs[0])), gojsRest...)`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("glue code is missing %q:\n%s", want, src)
+		}
+	}
+
+	c := config{Package: pkg, Host: "go-js/Go", Exports: exports}
+	for _, tt := range []struct {
+		tmpl *template.Template
+		want string
+	}{
+		{dtsTmpl, "sum(first: number, ...rest: number[]): number;"},
+		{jsTmpl, "sum: (first, ...rest) => go.exports.sum(first, ...rest),"},
+		{jsTmpl, "add: (a, b) => go.exports.add(a, b),"},
+	} {
+		out, err := execute(tt.tmpl, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), tt.want) {
+			t.Errorf("%s output is missing %q:\n%s", tt.tmpl.Name(), tt.want, out)
+		}
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		decl, err string
+	}{
+		{"func f(m map[string]int) {}", "unsupported type map[string]int"},
+		{"func f() (int, int) { return 0, 0 }", "at most one value"},
+		{"func f(p *int) {}", "unsupported type *int"},
+		{"type T struct{}\n\n//gojs:export\nfunc (T) f() {}", "only plain functions"},
+		{"func f() {}\n\n//gojs:export f\nfunc g() {}", "f is already exported by f"},
+		{"func f(this int) {}", "parameter this is a reserved word in JS"},
+	}
+	for _, tt := range tests {
+		dir := writePackage(t, "package main\n\n//gojs:export\n"+tt.decl+"\n\nfunc main() {}\n")
+		_, _, err := scan(dir)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %q", tt.decl, err, tt.err)
+		}
+	}
+}
+
+// TestGlueCompiles builds a package whose parameters are named like the
+// locals of the glue code for js/wasm.
+func TestGlueCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a package")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	// inside the module, so that the glue code can import interop
+	dir, err := os.MkdirTemp(".", "glue-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	src := `package main
+
+//gojs:export
+func clash(gojs int, r string, err bool, args ...int64) (int, error) { return 0, nil }
+
+//gojs:export
+func rest(i ...uint) {}
+
+func main() {}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, exports, err := scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := glue(config{Package: pkg, Exports: exports})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, glueFile), code, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "build", "-o", os.DevNull, "./"+filepath.Base(dir))
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building the glue code: %v\n%s\n%s", err, out, code)
+	}
+}
+
+func TestScanRegistered(t *testing.T) {
+	dir := writePackage(t, `package main
+
+import (
+	"syscall/js"
+
+	"go-to-js/interop"
+)
+
+// double doubles.
+func double(this js.Value, args []js.Value) any { return args[0].Int() * 2 }
+
+func main() {
+	interop.Export("double", double)
+	interop.Export("noop", func(this js.Value, args []js.Value) any { return nil })
+}
+`)
+	_, exports, err := scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 2 {
+		t.Fatalf("got %d exports, want 2: %+v", len(exports), exports)
+	}
+	if e := exports[0]; e.JSName != "double" || !e.Untyped || e.Doc != "double doubles." {
+		t.Errorf("double = %+v", e)
+	}
+	if e := exports[1]; e.JSName != "noop" || !e.Untyped || e.Doc != "" {
+		t.Errorf("noop = %+v", e)
+	}
+
+	c := config{Package: "main", Host: "go-js/Go", Exports: exports}
+	if len(c.Typed()) != 0 {
+		t.Errorf("registered exports are treated as typed")
+	}
+	dts, err := execute(dtsTmpl, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dts), "double(...args: any[]): any;") {
+		t.Errorf("declarations are missing double:\n%s", dts)
+	}
+}
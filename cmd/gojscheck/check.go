@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go-to-js/wasm"
+)
+
+var (
+	hostEntry    = regexp.MustCompile(`^'([^']+)',?$`)
+	releaseEntry = regexp.MustCompile(`^'([^']+)': '([^']+)',?$`)
+	newestGo     = regexp.MustCompile(`(?m)^const newestGo = '([^']+)';$`)
+)
+
+// entries returns the submatches of entry for each line between the line
+// open and the line close of Imports.js's source src, which lists one
+// entry per line. It fails on a line that isn't an entry, blank or a
+// comment, so that reformatting the list can't make entries go missing
+// silently.
+func entries(src []byte, open, close string, entry *regexp.Regexp) ([][]string, error) {
+	lines := strings.Split(string(src), "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == open {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no line %q", open)
+	}
+	var out [][]string
+	for i := start + 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == close:
+			if len(out) == 0 {
+				return nil, fmt.Errorf("no entries after %q", open)
+			}
+			return out, nil
+		case line == "" || strings.HasPrefix(line, "//"):
+		default:
+			m := entry.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: %q isn't an entry of %q", i+1, line, open)
+			}
+			out = append(out, m)
+		}
+	}
+	return nil, fmt.Errorf("no line %q closing %q", close, open)
+}
+
+// hostFunctions returns the functions Go.js implements, as listed by the
+// source src of its Imports.js.
+func hostFunctions(src []byte) (map[string]bool, error) {
+	list, err := entries(src, "const hostFunctions = [", "];", hostEntry)
+	if err != nil {
+		return nil, fmt.Errorf("hostFunctions: %v", err)
+	}
+	funcs := make(map[string]bool)
+	for _, m := range list {
+		funcs[m[1]] = true
+	}
+	return funcs, nil
+}
+
+// releases maps the functions Go imports from JS to the Go release that
+// introduced them, as the importReleases table of Imports.js's source src
+// has them, which was checked against Go releases up to newest.
+type releases struct {
+	names  map[string]string
+	newest string
+}
+
+func importReleases(src []byte) (*releases, error) {
+	table, err := entries(src, "const importReleases = {", "};", releaseEntry)
+	if err != nil {
+		return nil, fmt.Errorf("importReleases: %v", err)
+	}
+	newest := newestGo.FindSubmatch(src)
+	if newest == nil {
+		return nil, errors.New("no newestGo")
+	}
+	r := &releases{names: make(map[string]string), newest: string(newest[1])}
+	for _, m := range table {
+		r.names[m[1]] = m[2]
+	}
+	return r, nil
+}
+
+// of describes when Go started importing imp, given as module.name, the
+// way Imports.js's importRelease does.
+func (r *releases) of(imp string) string {
+	_, name, _ := strings.Cut(imp, ".")
+	if release, ok := r.names[name]; ok {
+		return release
+	}
+	return "not in Go up to " + r.newest
+}
+
+// result is what check finds in a module.
+type result struct {
+	// GoVersion is the Go that built the module, if it says.
+	GoVersion string
+	// Imports counts the functions the module imports from Go.js.
+	Imports int
+	// Missing are the imports Go.js doesn't have, as module.name.
+	Missing []string
+}
+
+// check compares the imports of m from Go.js with the functions host
+// implements. Modules of Go before 1.21 import from go instead of gojs,
+// which Go.js doesn't provide at all.
+func check(m *wasm.Module, host map[string]bool) (*result, error) {
+	imports, err := m.Imports()
+	if err != nil {
+		return nil, err
+	}
+	res := new(result)
+	if res.GoVersion, err = m.GoVersion(); err != nil {
+		return nil, err
+	}
+	for _, imp := range imports {
+		if imp.Module != "gojs" && imp.Module != "go" {
+			continue
+		}
+		res.Imports++
+		if imp.Module == "go" || !host[imp.Name] {
+			res.Missing = append(res.Missing, imp.Module+"."+imp.Name)
+		}
+	}
+	return res, nil
+}
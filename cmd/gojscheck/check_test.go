@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-to-js/wasm"
+)
+
+func goJS(t *testing.T) map[string]bool {
+	t.Helper()
+	src, err := os.ReadFile("../../Imports.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := hostFunctions(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return funcs
+}
+
+func TestHostFunctions(t *testing.T) {
+	funcs := goJS(t)
+	for _, name := range []string{"runtime.wasmExit", "runtime.getRandomData", "syscall/js.valueGet", "syscall/js.copyBytesToJS"} {
+		if !funcs[name] {
+			t.Errorf("%s missing from Go.js's hostFunctions %v", name, funcs)
+		}
+	}
+	if _, err := hostFunctions([]byte("const x = ['runtime.wasmExit'];")); err == nil {
+		t.Error("hostFunctions of a source without the list succeeded")
+	}
+
+	// a list the parser can't read entry by entry fails instead of coming
+	// out short
+	for _, src := range []string{
+		"const hostFunctions = ['runtime.wasmExit', 'runtime.wasmWrite'];",
+		"const hostFunctions = [\n  'runtime.wasmExit', 'runtime.wasmWrite',\n];",
+		"const hostFunctions = [\n  'runtime.wasmExit',\n  \"runtime.wasmWrite\",\n];",
+		"const hostFunctions = [\n  'runtime.wasmExit',\n",
+		"const hostFunctions = [\n];",
+	} {
+		if funcs, err := hostFunctions([]byte(src)); err == nil {
+			t.Errorf("hostFunctions(%q) = %v, want an error", src, funcs)
+		}
+	}
+	funcs, err := hostFunctions([]byte("const hostFunctions = [\n  // a ] in a comment\n  'a]b',\n\n  'c'\n];"))
+	if err != nil || !reflect.DeepEqual(funcs, map[string]bool{"a]b": true, "c": true}) {
+		t.Errorf("hostFunctions = %v, %v", funcs, err)
+	}
+}
+
+func TestImportReleases(t *testing.T) {
+	src, err := os.ReadFile("../../Imports.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := importReleases(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range goJS(t) {
+		if rel.names[name] == "" {
+			t.Errorf("%s missing from Go.js's importReleases", name)
+		}
+	}
+	for imp, want := range map[string]string{
+		"gojs.syscall/js.copyBytesToJS": "go1.13",
+		"go.runtime.walltime1":          "go1.16",
+		"gojs.runtime.newInGo2":         "not in Go up to " + rel.newest,
+	} {
+		if got := rel.of(imp); got != want {
+			t.Errorf("release of %s = %q, want %q", imp, got, want)
+		}
+	}
+	if _, err := importReleases([]byte("const hostFunctions = [];")); err == nil {
+		t.Error("importReleases of a source without the table succeeded")
+	}
+	if _, err := importReleases([]byte("const importReleases = {\n  'runtime.wasmExit': 'go1.11', 'runtime.wasmWrite': 'go1.11',\n};\nconst newestGo = 'go1.27';")); err == nil {
+		t.Error("importReleases of a table with two entries on a line succeeded")
+	}
+	if _, err := importReleases([]byte("const importReleases = {\n  'runtime.wasmExit': 'go1.11',\n};")); err == nil {
+		t.Error("importReleases of a source without newestGo succeeded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	imp := func(module, name string) []byte {
+		return append(wasm.AppendName(wasm.AppendName(nil, module), name), wasm.FuncKind, 0)
+	}
+	imports := wasm.AppendU32(nil, 4)
+	imports = append(imports, imp("gojs", "runtime.wasmExit")...)
+	imports = append(imports, imp("gojs", "runtime.newInGo2")...)
+	imports = append(imports, imp("go", "runtime.wasmWrite")...)
+	imports = append(imports, imp("env", "f")...)
+	m := &wasm.Module{Sections: []*wasm.Section{
+		{ID: wasm.TypeSection, Data: []byte{1, 0x60, 0, 0}},
+		{ID: wasm.ImportSection, Data: imports},
+	}}
+
+	res, err := check(m, map[string]bool{"runtime.wasmExit": true, "runtime.wasmWrite": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &result{Imports: 3, Missing: []string{"gojs.runtime.newInGo2", "go.runtime.wasmWrite"}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("check() = %+v, want %+v", res, want)
+	}
+}
+
+// TestGoProgram checks the module's program built for js/wasm against
+// Go.js, which fails once Go adds an import Go.js doesn't implement.
+func TestGoProgram(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the program")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	out := filepath.Join(t.TempDir(), "main.wasm")
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = "../.."
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building: %v\n%s", err, b)
+	}
+
+	res, err := checkFile(out, goJS(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imports == 0 || res.GoVersion == "" {
+		t.Errorf("check() = %+v, want imports and a Go version", res)
+	}
+	if len(res.Missing) > 0 {
+		t.Errorf("Go.js doesn't implement %v, imported by a program built with %s", res.Missing, res.GoVersion)
+	}
+}
@@ -0,0 +1,90 @@
+// Command gojscheck checks that Go.js implements every function wasm
+// binaries built with GOOS=js import from it, without running them. Go.js
+// itself refuses such binaries when it loads them; gojscheck finds out
+// before, e.g. after upgrading Go, for all binaries at once.
+//
+// The functions Go.js implements are read from the hostFunctions list of
+// its Imports.js. For each binary gojscheck prints the Go version that
+// built it and the imports Go.js is missing, each with the Go release that
+// introduced it from the importReleases table next to the list, and it
+// exits with 1 if any is.
+//
+// Usage:
+//
+//	gojscheck [-host Imports.js] file.wasm...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go-to-js/wasm"
+)
+
+var host = flag.String("host", "Imports.js", "path of Go.js's Imports.js")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gojscheck [-host Imports.js] file.wasm...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	src, err := os.ReadFile(*host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gojscheck: %v\n", err)
+		os.Exit(2)
+	}
+	funcs, err := hostFunctions(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gojscheck: %s: %v\n", *host, err)
+		os.Exit(2)
+	}
+	rel, err := importReleases(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gojscheck: %s: %v\n", *host, err)
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, file := range flag.Args() {
+		res, err := checkFile(file, funcs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gojscheck: %s: %v\n", file, err)
+			failed = true
+			continue
+		}
+		version := res.GoVersion
+		if version == "" {
+			version = "unknown Go version"
+		}
+		if len(res.Missing) == 0 {
+			fmt.Printf("%s (%s): ok, %d imports\n", file, version, res.Imports)
+			continue
+		}
+		failed = true
+		fmt.Printf("%s (%s): %d of %d imports missing from Go.js\n", file, version, len(res.Missing), res.Imports)
+		for _, name := range res.Missing {
+			fmt.Printf("\t%s (%s)\n", name, rel.of(name))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func checkFile(file string, funcs map[string]bool) (*result, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	m, err := wasm.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	return check(m, funcs)
+}
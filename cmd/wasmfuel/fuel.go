@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"go-to-js/wasm"
+)
+
+// fuelExport is the name of the global holding the fuel left.
+const fuelExport = "fuel"
+
+// instrument adds the fuel global to m and a check at the entry of each
+// function and loop.
+func instrument(m *wasm.Module) error {
+	exports, err := m.Exports()
+	if err != nil {
+		return err
+	}
+	for _, e := range exports {
+		if e.Name == fuelExport {
+			return errors.New("already instrumented, it exports " + fuelExport)
+		}
+	}
+	imported, err := m.ImportCount(wasm.GlobalKind)
+	if err != nil {
+		return err
+	}
+	globals, err := m.Globals()
+	if err != nil {
+		return err
+	}
+	funcs, err := m.Code()
+	if err != nil {
+		return err
+	}
+
+	fuel := uint32(imported + len(globals))
+	for i := range funcs {
+		code, err := meter(funcs[i].Code, fuel)
+		if err != nil {
+			return fmt.Errorf("function %d: %v", i, err)
+		}
+		funcs[i].Code = code
+	}
+
+	// without a budget set the program runs as before
+	init := wasm.AppendS64([]byte{byte(wasm.OpI64Const)}, math.MaxInt64)
+	globals = append(globals, wasm.Global{Type: wasm.I64, Mutable: true, Init: append(init, byte(wasm.OpEnd))})
+	m.SetGlobals(globals)
+	m.SetCode(funcs)
+	m.SetExports(append(exports, wasm.Export{Name: fuelExport, Kind: wasm.GlobalKind, Index: fuel}))
+	return nil
+}
+
+// meter returns code with a check at its start and at the start of each
+// loop body. Each check charges the instructions up to the next one.
+func meter(code []byte, fuel uint32) ([]byte, error) {
+	f := wasm.Func{Code: code}
+	ins, err := f.Instructions()
+	if err != nil {
+		return nil, err
+	}
+	points, costs := []int{0}, []int64{0}
+	for _, in := range ins {
+		costs[len(costs)-1]++
+		if in.Op == wasm.OpLoop {
+			points = append(points, in.End)
+			costs = append(costs, 0)
+		}
+	}
+
+	out := make([]byte, 0, len(code)+len(points)*20)
+	prev := 0
+	for i, p := range points {
+		out = append(out, code[prev:p]...)
+		out = appendCheck(out, fuel, costs[i])
+		prev = p
+	}
+	return append(out, code[prev:]...), nil
+}
+
+// appendCheck appends
+//
+//	global.set fuel (i64.sub (global.get fuel) (i64.const cost))
+//	if (i64.lt_s (global.get fuel) (i64.const 0)) unreachable
+func appendCheck(b []byte, fuel uint32, cost int64) []byte {
+	b = wasm.AppendU32(append(b, byte(wasm.OpGlobalGet)), fuel)
+	b = wasm.AppendS64(append(b, byte(wasm.OpI64Const)), cost)
+	b = append(b, byte(wasm.OpI64Sub))
+	b = wasm.AppendU32(append(b, byte(wasm.OpGlobalSet)), fuel)
+	b = wasm.AppendU32(append(b, byte(wasm.OpGlobalGet)), fuel)
+	return append(b,
+		byte(wasm.OpI64Const), 0,
+		byte(wasm.OpI64LtS),
+		byte(wasm.OpIf), wasm.BlockVoid,
+		byte(wasm.OpUnreachable),
+		byte(wasm.OpEnd),
+	)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"go-to-js/wasm"
+)
+
+// costs returns the costs charged by the checks in code, in order.
+func costs(t *testing.T, code []byte, fuel uint32) []int64 {
+	t.Helper()
+	f := wasm.Func{Code: code}
+	ins, err := f.Instructions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for i := 0; i+2 < len(ins); i++ {
+		if ins[i].Op == wasm.OpGlobalGet && ins[i].Index == fuel && ins[i+1].Op == wasm.OpI64Const && ins[i+2].Op == wasm.OpI64Sub {
+			got = append(got, wasm.NewReader(code[ins[i+1].Offset+1:]).S64())
+		}
+	}
+	return got
+}
+
+func TestMeter(t *testing.T) {
+	code := []byte{
+		byte(wasm.OpI32Const), 1,
+		byte(wasm.OpLoop), wasm.BlockVoid,
+		byte(wasm.OpCall), 0,
+		byte(wasm.OpLoop), wasm.BlockVoid,
+		byte(wasm.OpI32Const), 0,
+		byte(wasm.OpBrIf), 0,
+		byte(wasm.OpEnd),
+		byte(wasm.OpI32Const), 0,
+		byte(wasm.OpBrIf), 0,
+		byte(wasm.OpEnd),
+		0x1a, // drop
+		byte(wasm.OpEnd),
+	}
+	got, err := meter(code, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the entry charges up to the outer loop, which charges up to the
+	// inner loop, which charges up to the end of the function
+	want := []int64{2, 2, 8}
+	if c := costs(t, got, 7); len(c) != len(want) || c[0] != want[0] || c[1] != want[1] || c[2] != want[2] {
+		t.Errorf("check costs %v, want %v", c, want)
+	}
+	if len(got) != len(code)+3*len(appendCheck(nil, 7, 0)) {
+		t.Errorf("metered code is %d bytes, want the code plus three checks", len(got))
+	}
+	if !bytes.HasPrefix(got, appendCheck(nil, 7, 2)) {
+		t.Errorf("metered code %x doesn't start with a check", got)
+	}
+	if !bytes.HasSuffix(got, code[len(code)-2:]) {
+		t.Errorf("metered code %x doesn't end as the code did", got)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	// a function of type 0, exported as run, that loops while its param
+	// is not zero
+	b := []byte{0, 'a', 's', 'm', 1, 0, 0, 0}
+	b = append(b, wasm.TypeSection, 5, 1, 0x60, 1, wasm.I32, 0)
+	b = append(b, wasm.FunctionSection, 2, 1, 0)
+	b = append(b, wasm.GlobalSection, 6, 1, wasm.I32, 1, byte(wasm.OpI32Const), 0, byte(wasm.OpEnd))
+	b = append(b, wasm.ExportSection, 7, 1, 3, 'r', 'u', 'n', wasm.FuncKind, 0)
+	b = append(b, wasm.CodeSection, 11, 1, 9, 0,
+		byte(wasm.OpLoop), wasm.BlockVoid,
+		0x20, 0, // local.get 0
+		byte(wasm.OpBrIf), 0,
+		byte(wasm.OpEnd),
+		byte(wasm.OpEnd),
+	)
+	m, err := wasm.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := instrument(m); err != nil {
+		t.Fatal(err)
+	}
+	m, err = wasm.Parse(m.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globals, err := m.Globals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	init := wasm.AppendS64([]byte{byte(wasm.OpI64Const)}, math.MaxInt64)
+	if len(globals) != 2 || globals[1].Type != wasm.I64 || !globals[1].Mutable || !bytes.Equal(globals[1].Init, append(init, byte(wasm.OpEnd))) {
+		t.Errorf("globals %+v, want a second, mutable i64 one starting at the largest i64", globals)
+	}
+	exports, err := m.Exports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 2 || exports[1] != (wasm.Export{Name: fuelExport, Kind: wasm.GlobalKind, Index: 1}) {
+		t.Errorf("exports %+v, want fuel exported as global 1", exports)
+	}
+	funcs, err := m.Code()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := costs(t, funcs[0].Code, 1); len(c) != 2 || c[0] != 1 || c[1] != 4 {
+		t.Errorf("check costs %v, want [1 4]", c)
+	}
+
+	if err := instrument(m); err == nil {
+		t.Error("instrumenting twice succeeded, want an error")
+	}
+}
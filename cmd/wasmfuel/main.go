@@ -0,0 +1,63 @@
+// Command wasmfuel rewrites a wasm binary to meter its execution with fuel,
+// for Go.js's fuel option to give untrusted input a compute budget that,
+// unlike a timeout, doesn't depend on the machine.
+//
+// The fuel left is an exported mutable i64 global, fuel, starting at the
+// largest i64. A check at the entry of every function and at the start of
+// every loop iteration subtracts the number of instructions from there to
+// the next check in the code and traps with unreachable once the fuel
+// drops below zero. The cost is an estimate of the work between checks: it
+// counts branches that skip code as taken, calls are charged by the callee.
+//
+// Usage:
+//
+//	wasmfuel [-o out.wasm] in.wasm
+//
+// The output defaults to in-fuel.wasm next to the input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-to-js/wasm"
+)
+
+var out = flag.String("o", "", "output file, <input>-fuel.wasm by default")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: wasmfuel [-o out.wasm] in.wasm\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "wasmfuel: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in string) error {
+	b, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	m, err := wasm.Parse(b)
+	if err != nil {
+		return fmt.Errorf("%s: %v", in, err)
+	}
+	if err := instrument(m); err != nil {
+		return fmt.Errorf("%s: %v", in, err)
+	}
+	dst := *out
+	if dst == "" {
+		dst = strings.TrimSuffix(in, ".wasm") + "-fuel.wasm"
+	}
+	return os.WriteFile(dst, m.Bytes(), 0o644)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"go-to-js/wasm"
+)
+
+// hostModules are the import modules of the Go.js host: gojs since Go 1.21
+// and go before.
+var hostModules = map[string]bool{"gojs": true, "go": true}
+
+var kindNames = map[byte]string{
+	wasm.FuncKind:   "func",
+	wasm.TableKind:  "table",
+	wasm.MemoryKind: "memory",
+	wasm.GlobalKind: "global",
+}
+
+// report is what wasminspect finds in a module.
+type report struct {
+	Size     int            `json:"size"`
+	Sections []sectionSize  `json:"sections"`
+	Imports  []importEntry  `json:"imports"`
+	Exports  []exportEntry  `json:"exports"`
+	Code     int            `json:"code"`
+	Packages []packageSize  `json:"packages"`
+	Funcs    []functionSize `json:"functions"`
+	Data     dataSize       `json:"data"`
+	// NumPackages and NumFuncs count those left out of Packages and Funcs
+	// by -top too.
+	NumPackages int `json:"numPackages"`
+	NumFuncs    int `json:"numFunctions"`
+	// HostImports are the names of the imports Go.js has to implement.
+	HostImports []string `json:"hostImports"`
+}
+
+type sectionSize struct {
+	ID   byte   `json:"id"`
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+type importEntry struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+}
+
+type exportEntry struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Index uint32 `json:"index"`
+}
+
+type packageSize struct {
+	Name  string `json:"name"`
+	Size  int    `json:"size"`
+	Funcs int    `json:"funcs"`
+}
+
+type functionSize struct {
+	Index   uint32 `json:"index"`
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	Size    int    `json:"size"`
+}
+
+type dataSize struct {
+	Segments int `json:"segments"`
+	Size     int `json:"size"`
+}
+
+var sectionNames = []string{"custom", "type", "import", "function", "table", "memory", "global", "export", "start", "element", "code", "data", "datacount"}
+
+// inspect reports on the module b, listing the top packages and functions
+// by code size, or all of them if top is 0.
+func inspect(b []byte, top int) (*report, error) {
+	m, err := wasm.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	rep := &report{Size: len(b)}
+	for _, s := range m.Sections {
+		name := s.Name
+		if s.ID != wasm.CustomSection && int(s.ID) < len(sectionNames) {
+			name = sectionNames[s.ID]
+		}
+		rep.Sections = append(rep.Sections, sectionSize{s.ID, name, len(s.Data)})
+	}
+
+	imports, err := m.Imports()
+	if err != nil {
+		return nil, err
+	}
+	host := make(map[string]bool)
+	for _, imp := range imports {
+		rep.Imports = append(rep.Imports, importEntry{imp.Module, imp.Name, kindNames[imp.Kind]})
+		if hostModules[imp.Module] && !host[imp.Name] {
+			host[imp.Name] = true
+			rep.HostImports = append(rep.HostImports, imp.Name)
+		}
+	}
+	sort.Strings(rep.HostImports)
+	exports, err := m.Exports()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range exports {
+		rep.Exports = append(rep.Exports, exportEntry{e.Name, kindNames[e.Kind], e.Index})
+	}
+
+	funcs, err := m.Code()
+	if err != nil {
+		return nil, err
+	}
+	names, err := m.FuncNames()
+	if err != nil {
+		return nil, err
+	}
+	imported, _ := m.ImportCount(wasm.FuncKind)
+	byPackage := make(map[string]*packageSize)
+	for i, f := range funcs {
+		index := uint32(imported + i)
+		name, ok := names[index]
+		if !ok {
+			name = fmt.Sprintf("func[%d]", index)
+		}
+		fs := functionSize{Index: index, Name: name, Package: wasm.GoPackage(name), Size: len(f.Locals) + len(f.Code)}
+		rep.Code += fs.Size
+		rep.Funcs = append(rep.Funcs, fs)
+		p := byPackage[fs.Package]
+		if p == nil {
+			p = &packageSize{Name: fs.Package}
+			byPackage[fs.Package] = p
+		}
+		p.Size += fs.Size
+		p.Funcs++
+	}
+	for _, p := range byPackage {
+		rep.Packages = append(rep.Packages, *p)
+	}
+	sort.Slice(rep.Packages, func(i, j int) bool {
+		a, b := rep.Packages[i], rep.Packages[j]
+		return a.Size > b.Size || a.Size == b.Size && a.Name < b.Name
+	})
+	sort.SliceStable(rep.Funcs, func(i, j int) bool {
+		return rep.Funcs[i].Size > rep.Funcs[j].Size
+	})
+	rep.NumPackages, rep.NumFuncs = len(rep.Packages), len(rep.Funcs)
+	if top > 0 {
+		rep.Packages = rep.Packages[:min(top, len(rep.Packages))]
+		rep.Funcs = rep.Funcs[:min(top, len(rep.Funcs))]
+	}
+
+	data, err := m.Data()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range data {
+		rep.Data.Segments++
+		rep.Data.Size += len(d.Init)
+	}
+	return rep, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-to-js/wasm"
+)
+
+// testModule builds a module importing from gojs like a Go program does,
+// with three functions named by a name section and two data segments.
+func testModule() []byte {
+	m := &wasm.Module{}
+	add := func(id byte, name string, data []byte) {
+		m.Sections = append(m.Sections, &wasm.Section{ID: id, Name: name, Data: data})
+	}
+	vec := func(n int, items ...[]byte) []byte {
+		return append(wasm.AppendU32(nil, uint32(n)), bytes.Join(items, nil)...)
+	}
+	imp := func(module, name string) []byte {
+		return append(wasm.AppendName(wasm.AppendName(nil, module), name), wasm.FuncKind, 0)
+	}
+	body := func(size int) []byte {
+		// no locals, size-2 nops and end
+		code := append([]byte{0}, bytes.Repeat([]byte{0x01}, size-2)...)
+		return append(wasm.AppendU32(nil, uint32(size)), append(code, byte(wasm.OpEnd))...)
+	}
+	name := func(i uint32, s string) []byte {
+		return wasm.AppendName(wasm.AppendU32(nil, i), s)
+	}
+
+	add(wasm.TypeSection, "", []byte{1, 0x60, 0, 0})
+	add(wasm.ImportSection, "", vec(3,
+		imp("gojs", "syscall/js.valueGet"),
+		imp("gojs", "runtime.wasmExit"),
+		imp("gojs", "runtime.wasmExit"),
+	))
+	add(wasm.FunctionSection, "", []byte{3, 0, 0, 0})
+	add(wasm.ExportSection, "", vec(1, append(wasm.AppendName(nil, "run"), wasm.FuncKind, 4)))
+	add(wasm.CodeSection, "", vec(3, body(100), body(10), body(30)))
+	add(wasm.DataSection, "", vec(2,
+		[]byte{0, byte(wasm.OpI32Const), 0, byte(wasm.OpEnd), 3, 'a', 'b', 'c'},
+		[]byte{1, 2, 'd', 'e'},
+	))
+	names := vec(3, name(3, "runtime.mallocgc"), name(4, "main.main"), name(5, "runtime.__mspan_.init"))
+	add(wasm.CustomSection, "name", append(append([]byte{1}, wasm.AppendU32(nil, uint32(len(names)))...), names...))
+	return m.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	b := testModule()
+	rep, err := inspect(b, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Size != len(b) || len(rep.Sections) != 7 || rep.Sections[6].Name != "name" || rep.Sections[4].Name != "code" {
+		t.Errorf("size %d, sections %+v", rep.Size, rep.Sections)
+	}
+	if rep.Code != 140 {
+		t.Errorf("code size %d, want 140", rep.Code)
+	}
+	wantPackages := []packageSize{{"runtime", 130, 2}, {"main", 10, 1}}
+	if !reflect.DeepEqual(rep.Packages, wantPackages) || rep.NumPackages != 2 {
+		t.Errorf("packages %+v, want %+v", rep.Packages, wantPackages)
+	}
+	wantFuncs := []functionSize{
+		{3, "runtime.mallocgc", "runtime", 100},
+		{5, "runtime.__mspan_.init", "runtime", 30},
+		{4, "main.main", "main", 10},
+	}
+	if !reflect.DeepEqual(rep.Funcs, wantFuncs) || rep.NumFuncs != 3 {
+		t.Errorf("functions %+v, want %+v", rep.Funcs, wantFuncs)
+	}
+	if rep.Data != (dataSize{2, 5}) {
+		t.Errorf("data %+v, want 5 bytes in 2 segments", rep.Data)
+	}
+	if want := []string{"runtime.wasmExit", "syscall/js.valueGet"}; !reflect.DeepEqual(rep.HostImports, want) {
+		t.Errorf("Go.js imports %v, want %v", rep.HostImports, want)
+	}
+	if len(rep.Imports) != 3 || len(rep.Exports) != 1 || rep.Exports[0] != (exportEntry{"run", "func", 4}) {
+		t.Errorf("imports %+v, exports %+v", rep.Imports, rep.Exports)
+	}
+}
+
+func TestInspectTop(t *testing.T) {
+	rep, err := inspect(testModule(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Packages) != 1 || rep.NumPackages != 2 || len(rep.Funcs) != 1 || rep.Funcs[0].Name != "runtime.mallocgc" || rep.NumFuncs != 3 {
+		t.Errorf("top 1: packages %+v of %d, functions %+v of %d", rep.Packages, rep.NumPackages, rep.Funcs, rep.NumFuncs)
+	}
+	if _, err := json.Marshal(rep); err != nil {
+		t.Error(err)
+	}
+
+	var out bytes.Buffer
+	if err := writeText(&out, "test.wasm", rep); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"test.wasm: ", "packages by code size, 1 of 2", "130  92.9%      2  runtime", "Go.js imports:\n  runtime.wasmExit\n  syscall/js.valueGet\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Command wasminspect reports what a wasm binary is made of, to find out
+// where the megabytes of a Go program built for js/wasm go and what it
+// needs from Go.js.
+//
+// It lists the module's sections, imports and exports, its code size by Go
+// package and by function, as named by the name section, and the size of
+// its data segments, which hold the program's static data and the
+// runtime's tables and aren't attributed to packages. The imports of the
+// gojs module are listed once more by name, as the functions Go.js has to
+// implement for the program to run.
+//
+// Package names come from the function names, which the Go linker writes
+// with underscores for slashes and other punctuation: go_to_js_events
+// stands for go-to-js/events.
+//
+// Usage:
+//
+//	wasminspect [-top n] [-format text|json] file.wasm
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+var (
+	top    = flag.Int("top", 20, "number of packages and functions to list, 0 for all")
+	format = flag.String("format", "text", "output format: text or json")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: wasminspect [-top n] [-format text|json] file.wasm\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *format != "text" && *format != "json" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	b, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wasminspect: %v\n", err)
+		os.Exit(1)
+	}
+	rep, err := inspect(b, *top)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wasminspect: %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+	if *format == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(rep)
+	} else {
+		err = writeText(os.Stdout, flag.Arg(0), rep)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wasminspect: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeText writes rep as tables.
+func writeText(w io.Writer, file string, rep *report) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	percent := func(n, of int) string {
+		return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(of))
+	}
+
+	fmt.Fprintf(tw, "%s: %d bytes\n\n", file, rep.Size)
+	fmt.Fprintf(tw, "size\t%%\t\tsection\n")
+	for _, s := range rep.Sections {
+		fmt.Fprintf(tw, "%d\t%s\t\t%s\n", s.Size, percent(s.Size, rep.Size), s.Name)
+	}
+
+	fmt.Fprintf(tw, "\npackages by code size, %d of %d\n", len(rep.Packages), rep.NumPackages)
+	fmt.Fprintf(tw, "size\t%%\tfuncs\t\tpackage\n")
+	for _, p := range rep.Packages {
+		name := p.Name
+		if name == "" {
+			name = "(no package)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t\t%s\n", p.Size, percent(p.Size, rep.Code), p.Funcs, name)
+	}
+
+	fmt.Fprintf(tw, "\nfunctions by code size, %d of %d\n", len(rep.Funcs), rep.NumFuncs)
+	fmt.Fprintf(tw, "size\t%%\tindex\t\tfunction\n")
+	for _, f := range rep.Funcs {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t\t%s\n", f.Size, percent(f.Size, rep.Code), f.Index, f.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\ndata: %d bytes in %d segments\n", rep.Data.Size, rep.Data.Segments)
+	fmt.Fprintf(w, "\nimports:\n")
+	for _, imp := range rep.Imports {
+		fmt.Fprintf(w, "  %s %s.%s\n", imp.Kind, imp.Module, imp.Name)
+	}
+	fmt.Fprintf(w, "\nexports:\n")
+	for _, e := range rep.Exports {
+		fmt.Fprintf(w, "  %s %s (%d)\n", e.Kind, e.Name, e.Index)
+	}
+	fmt.Fprintf(w, "\nGo.js imports:\n")
+	for _, name := range rep.HostImports {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
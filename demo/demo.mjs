@@ -0,0 +1,43 @@
+// Runs main.wasm (npm run build:go) with -serve and renders the results
+// of its exported fib function. Serve the repository root, e.g. with
+// npm run demo, and open /demo/.
+import Go from '../esm/web.mjs';
+
+const algorithms = ['recursive', 'iterative', 'memo', 'matrix', 'big'];
+
+const $ = (id) => document.getElementById(id);
+
+algorithms.forEach((algo) => {
+  $('algo').add(new Option(algo, algo, algo === 'big', algo === 'big'));
+});
+
+const render = (fib) => {
+  const n = Number($('n').value);
+  const algo = $('algo').value;
+  const rows = [];
+  const start = performance.now();
+  try {
+    for (let i = 0; i <= n; i++) {
+      rows.push(`<tr><td>${i}</td><td>${fib(i, algo)}</td></tr>`);
+    }
+    $('status').textContent = `fib(0..${n}) with ${algo} in ${(performance.now() - start).toFixed(1)} ms`;
+  } catch (err) {
+    $('status').textContent = `fib(${rows.length}) with ${algo}: ${err.message}`;
+  }
+  $('results').innerHTML = rows.join('');
+};
+
+const go = new Go('../main.wasm');
+go.run('-serve').then(({ code }) => {
+  $('status').textContent = `Go program exited with code ${code}`;
+}, (err) => {
+  $('status').textContent = `running main.wasm: ${err.message}`;
+});
+
+go.waitReady().then(() => {
+  $('form').addEventListener('submit', (event) => {
+    event.preventDefault();
+    render(go.exports.fib);
+  });
+  render(go.exports.fib);
+});
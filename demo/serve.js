@@ -0,0 +1,35 @@
+// Serves the repository root for the demo page, which needs http rather
+// than file URLs to load modules and main.wasm.
+//
+//   npm run build:go && npm run demo -- [port]
+const fs = require('fs');
+const http = require('http');
+const path = require('path');
+
+const root = path.join(__dirname, '..');
+const port = Number(process.argv[2]) || 8080;
+
+const types = {
+  '.html': 'text/html; charset=utf-8',
+  '.js': 'text/javascript',
+  '.mjs': 'text/javascript',
+  '.wasm': 'application/wasm',
+};
+
+http.createServer((req, res) => {
+  let file = path.join(root, path.posix.normalize(decodeURIComponent(new URL(req.url, 'http://localhost').pathname)));
+  if (fs.existsSync(file) && fs.statSync(file).isDirectory()) {
+    file = path.join(file, 'index.html');
+  }
+  fs.readFile(file, (err, data) => {
+    if (err) {
+      res.writeHead(404);
+      res.end('not found');
+      return;
+    }
+    res.writeHead(200, { 'Content-Type': types[path.extname(file)] || 'application/octet-stream' });
+    res.end(data);
+  });
+}).listen(port, () => {
+  console.log(`demo at http://localhost:${port}/demo/`);
+});
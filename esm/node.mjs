@@ -0,0 +1,5 @@
+// ES module entry for Node, the same class require('go-js/Go') returns.
+import Go from '../Go.js';
+
+export default Go;
+export const { GoPanicError, GoMemoryError, GoFuelError, GoImportError } = Go;
@@ -0,0 +1,30 @@
+// ES module entry for browsers and Deno. Go.js and its companions aren't
+// ES modules: loaded outside CommonJS they put what they export on
+// globalThis, where Go.js, loaded after the parts it is made of, and this
+// take them from before handing Go the web platform.
+import '../Emitter.js';
+import '../VirtualClock.js';
+import '../WasmBinary.js';
+import '../Imports.js';
+import '../Tracer.js';
+import '../Symbols.js';
+import '../Profiler.js';
+import '../Go.js';
+import platform from '../platform/web.mjs';
+
+const Go = globalThis.Go;
+Go.Tracer = globalThis.GoTracer;
+Go.Symbols = globalThis.GoSymbols;
+Go.Profiler = globalThis.GoProfiler;
+delete globalThis.Go;
+delete globalThis.GoTracer;
+delete globalThis.GoSymbols;
+delete globalThis.GoProfiler;
+delete globalThis.GoEmitter;
+delete globalThis.GoVirtualClock;
+delete globalThis.GoWasmBinary;
+delete globalThis.GoImports;
+Go.usePlatform(platform);
+
+export default Go;
+export const { GoPanicError, GoMemoryError, GoFuelError, GoImportError } = Go;
@@ -0,0 +1,20 @@
+// Package events emits named events with JSON payloads to the Go.js host,
+// which emits them on the Go instance running the program, an
+// EventEmitter:
+//
+//	events.Emit("stage", map[string]any{"name": "parse"})
+//
+//	go.on('stage', ({ name }) => console.log(name));
+//
+// Listeners run while Emit waits for them, so they see the program's
+// events as they happen. A Tracker reports progress through a long task as
+// "progress" events without emitting on every step.
+//
+// The instance emits "exit" itself, and EventEmitter gives "error",
+// "newListener" and "removeListener" their own meaning, so Emit refuses
+// those names and Go.js refuses them from any other caller too.
+//
+// Outside Go.js, in native and WASI builds or under wasm_exec.js, there is
+// no host to emit to: Emit does nothing and Listening reports false, so
+// programs can emit unconditionally.
+package events
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reserved are the names the Go instance emits itself or that Node's
+// EventEmitter treats specially: an "error" without listeners throws.
+var reserved = map[string]bool{
+	"exit":           true,
+	"error":          true,
+	"newListener":    true,
+	"removeListener": true,
+}
+
+// Emit emits the event name with payload encoded as JSON. It returns an
+// error if name is reserved, payload can't be encoded or a listener
+// throws.
+func Emit(name string, payload any) error {
+	if reserved[name] {
+		return fmt.Errorf("events: %q is reserved for the host", name)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return emit(name, string(data))
+}
+
+// Listening reports whether the host has listeners for name, for events
+// that are costly to build.
+func Listening(name string) bool {
+	return !reserved[name] && listening(name)
+}
+
+// Progress is the payload of "progress" events.
+type Progress struct {
+	Task    string `json:"task"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Percent int    `json:"percent"`
+}
+
+// Tracker emits "progress" events for a task of a number of steps, one
+// whenever the whole percentage done changes, so Set can be called on
+// every step.
+type Tracker struct {
+	task    string
+	total   int
+	percent int
+}
+
+// NewTracker returns a Tracker for task, e.g. "fib(100000)", taking total
+// steps.
+func NewTracker(task string, total int) *Tracker {
+	return &Tracker{task: task, total: total, percent: -1}
+}
+
+// Set records that done of the steps are done.
+func (t *Tracker) Set(done int) {
+	percent := 100
+	if done < t.total {
+		percent = done * 100 / t.total
+	}
+	if percent == t.percent {
+		return
+	}
+	t.percent = percent
+	// progress is informational, a failing listener doesn't fail the task
+	Emit("progress", Progress{Task: t.task, Done: done, Total: t.total, Percent: percent})
+}
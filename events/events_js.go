@@ -0,0 +1,22 @@
+package events
+
+import (
+	"syscall/js"
+
+	"go-to-js/interop"
+)
+
+// host is what Go.js provides, undefined under other hosts.
+var host = js.Global().Get("GoEvents")
+
+func emit(name, payload string) error {
+	if host.IsUndefined() {
+		return nil
+	}
+	_, err := interop.Call(host, "emit", name, payload)
+	return err
+}
+
+func listening(name string) bool {
+	return !host.IsUndefined() && host.Call("listening", name).Bool()
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"errors"
+	"syscall/js"
+	"testing"
+
+	"go-to-js/interop"
+)
+
+// fakeHost replaces Go.js's GoEvents with one recording what is emitted,
+// throwing a GoPanicError for events named "throw".
+func fakeHost(t *testing.T) *[]string {
+	var got []string
+	emitFn := interop.Func(func(this js.Value, args []js.Value) any {
+		if args[0].String() == "throw" {
+			panic("listener failed")
+		}
+		got = append(got, args[0].String()+" "+args[1].String())
+		return nil
+	})
+	listeningFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		return args[0].String() == "progress"
+	})
+	fake := js.Global().Get("Object").New()
+	fake.Set("emit", emitFn)
+	fake.Set("listening", listeningFn)
+
+	saved := host
+	host = fake
+	t.Cleanup(func() {
+		host = saved
+		emitFn.Release()
+		listeningFn.Release()
+	})
+	return &got
+}
+
+func TestEmit(t *testing.T) {
+	got := fakeHost(t)
+	if err := Emit("stage", map[string]any{"name": "parse", "n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	want := `stage {"n":1,"name":"parse"}`
+	if len(*got) != 1 || (*got)[0] != want {
+		t.Errorf("emitted %q, want [%q]", *got, want)
+	}
+	if !Listening("progress") || Listening("result") {
+		t.Error("Listening doesn't ask the host")
+	}
+}
+
+func TestEmitReturnsListenerError(t *testing.T) {
+	fakeHost(t)
+	err := Emit("throw", nil)
+	var jsErr *interop.JSError
+	if !errors.As(err, &jsErr) || jsErr.Message != "panic: listener failed" {
+		t.Errorf("err = %v, want the listener's error", err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	got := fakeHost(t)
+	tracker := NewTracker("task", 400)
+	for done := 0; done <= 400; done++ {
+		tracker.Set(done)
+	}
+	if len(*got) != 101 {
+		t.Fatalf("%d events, want one per percent", len(*got))
+	}
+	for i, want := range map[int]string{
+		0:   `progress {"task":"task","done":0,"total":400,"percent":0}`,
+		1:   `progress {"task":"task","done":4,"total":400,"percent":1}`,
+		100: `progress {"task":"task","done":400,"total":400,"percent":100}`,
+	} {
+		if (*got)[i] != want {
+			t.Errorf("event %d = %s, want %s", i, (*got)[i], want)
+		}
+	}
+}
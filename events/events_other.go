@@ -0,0 +1,11 @@
+//go:build !js
+
+package events
+
+func emit(name, payload string) error {
+	return nil
+}
+
+func listening(name string) bool {
+	return false
+}
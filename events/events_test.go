@@ -0,0 +1,20 @@
+package events
+
+import "testing"
+
+func TestEmitEncodingError(t *testing.T) {
+	if err := Emit("bad", make(chan int)); err == nil {
+		t.Error("Emit of a channel succeeded, want an encoding error")
+	}
+}
+
+func TestEmitReserved(t *testing.T) {
+	for _, name := range []string{"exit", "error", "newListener", "removeListener"} {
+		if err := Emit(name, 1); err == nil {
+			t.Errorf("Emit(%q) succeeded, want an error", name)
+		}
+		if Listening(name) {
+			t.Errorf("Listening(%q) = true", name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"go-to-js/events"
+)
+
+// maxFib is the largest n whose Fibonacci number fits in an int64.
+const maxFib = 92
+
+// trackFrom is the smallest n for which fibBig reports its progress.
+const trackFrom = 10000
+
+var errOverflow = errors.New("result overflows int64, use -algo big")
+
+// algorithms are the ways Main.go can compute fib(n), selected with -algo.
+var algorithms = map[string]func(n int) (*big.Int, error){
+	"recursive": intAlgorithm(fib),
+	"iterative": intAlgorithm(fibIterative),
+	"memo":      intAlgorithm(fibMemo),
+	"matrix":    intAlgorithm(fibMatrix),
+	"big":       fibBig,
+}
+
+func intAlgorithm(f func(n int) int) func(n int) (*big.Int, error) {
+	return func(n int) (*big.Int, error) {
+		if n > maxFib {
+			return nil, errOverflow
+		}
+		return big.NewInt(int64(f(n))), nil
+	}
+}
+
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+func fibIterative(n int) int {
+	a, b := 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
+
+func fibMemo(n int) int {
+	memo := make(map[int]int, n)
+	var f func(n int) int
+	f = func(n int) int {
+		if n < 2 {
+			return n
+		}
+		if v, ok := memo[n]; ok {
+			return v
+		}
+		v := f(n-1) + f(n-2)
+		memo[n] = v
+		return v
+	}
+	return f(n)
+}
+
+// fibMatrix raises [[1 1] [1 0]] to the nth power by repeated squaring.
+func fibMatrix(n int) int {
+	a, b, c, d := 1, 0, 0, 1 // result, starting at the identity
+	x, y, z, w := 1, 1, 1, 0
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			a, b, c, d = a*x+b*z, a*y+b*w, c*x+d*z, c*y+d*w
+		}
+		x, y, z, w = x*x+y*z, x*y+y*w, z*x+w*z, z*y+w*w
+	}
+	return b
+}
+
+func fibBig(n int) (*big.Int, error) {
+	var progress *events.Tracker
+	if n >= trackFrom {
+		progress = events.NewTracker(fmt.Sprintf("fib(%d)", n), n)
+	}
+	a, b := big.NewInt(0), big.NewInt(1)
+	for i := 0; i < n; i++ {
+		if progress != nil {
+			progress.Set(i)
+		}
+		a.Add(a, b)
+		a, b = b, a
+	}
+	if progress != nil {
+		progress.Set(n)
+	}
+	return a, nil
+}
+
+// lucas computes the nth Lucas number as fib(n-1) + fib(n+1), with f
+// computing fib. The sum is exact even where it no longer fits an int64.
+func lucas(f func(n int) (*big.Int, error), n int) (*big.Int, error) {
+	if n == 0 {
+		return big.NewInt(2), nil
+	}
+	a, err := f(n - 1)
+	if err != nil {
+		return nil, err
+	}
+	b, err := f(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Add(a, b), nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"math/big"
+	"testing"
+)
+
+func TestAlgorithmsAgree(t *testing.T) {
+	want := []int64{0, 1, 1, 2, 3, 5, 8, 13, 21, 34, 55}
+	for name, f := range algorithms {
+		for n, w := range want {
+			got, err := f(n)
+			if err != nil || got.Int64() != w {
+				t.Errorf("%s(%d) = %v, %v, want %d", name, n, got, err, w)
+			}
+		}
+	}
+
+	for name, f := range algorithms {
+		if name == "recursive" {
+			continue
+		}
+		want, _ := fibBig(maxFib)
+		if got, err := f(maxFib); err != nil || got.Cmp(want) != 0 {
+			t.Errorf("%s(%d) = %v, %v, want %v", name, maxFib, got, err, want)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	for name, f := range algorithms {
+		_, err := f(maxFib + 1)
+		if name == "big" {
+			if err != nil {
+				t.Errorf("big(%d): %v", maxFib+1, err)
+			}
+			continue
+		}
+		if err != errOverflow {
+			t.Errorf("%s(%d) error = %v, want %v", name, maxFib+1, err, errOverflow)
+		}
+	}
+
+	want, _ := new(big.Int).SetString("12200160415121876738", 10)
+	if got, _ := fibBig(maxFib + 1); got.Cmp(want) != 0 {
+		t.Errorf("big(%d) = %v, want %v", maxFib+1, got, want)
+	}
+}
+
+func TestLucas(t *testing.T) {
+	want := []int64{2, 1, 3, 4, 7, 11, 18, 29, 47, 76, 123}
+	for name, f := range algorithms {
+		for n, w := range want {
+			got, err := lucas(f, n)
+			if err != nil || got.Int64() != w {
+				t.Errorf("lucas(%s, %d) = %v, %v, want %d", name, n, got, err, w)
+			}
+		}
+	}
+	if _, err := lucas(algorithms["iterative"], maxFib); err != errOverflow {
+		t.Errorf("lucas(iterative, %d) error = %v, want %v", maxFib, err, errOverflow)
+	}
+}
+
+var benchN = flag.Int("fib.n", 25, "n used by BenchmarkAlgorithms")
+
+func BenchmarkAlgorithms(b *testing.B) {
+	for _, name := range algorithmNames() {
+		f := algorithms[name]
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := f(*benchN); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1 +1,3 @@
 module go-to-js
+
+go 1.21
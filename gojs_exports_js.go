@@ -0,0 +1,40 @@
+// Code generated by gojsbuild. DO NOT EDIT.
+
+package main
+
+import (
+	"syscall/js"
+
+	"go-to-js/interop"
+)
+
+func init() {
+	interop.Export("fib", func(gojsThis js.Value, gojsArgs []js.Value) any {
+		// trailing undefined arguments count as left out, as in JS
+		for len(gojsArgs) > 1 && gojsArgs[len(gojsArgs)-1].IsUndefined() {
+			gojsArgs = gojsArgs[:len(gojsArgs)-1]
+		}
+		if len(gojsArgs) < 1 {
+			panic("fib: want at least 1 arguments")
+		}
+		gojsRest := make([]string, len(gojsArgs)-1)
+		for gojsI := range gojsRest {
+			gojsRest[gojsI] = gojsArgs[1+gojsI].String()
+		}
+		gojsR, gojsErr := exportedFib(int(interop.Int64(gojsArgs[0])), gojsRest...)
+		if gojsErr != nil {
+			panic(gojsErr)
+		}
+		return gojsR
+	})
+	interop.Export("fibString", func(gojsThis js.Value, gojsArgs []js.Value) any {
+		if len(gojsArgs) < 2 {
+			panic("fibString: want 2 arguments")
+		}
+		gojsR, gojsErr := fibString(int(interop.Int64(gojsArgs[0])), gojsArgs[1].String())
+		if gojsErr != nil {
+			panic(gojsErr)
+		}
+		return gojsR
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// servedLimit is the largest n fibHandler computes fib of with algo, so
+// that a single request can't keep the program busy for long. recursive
+// takes time exponential in n.
+func servedLimit(algo string) int {
+	if algo == "recursive" {
+		return 40
+	}
+	return 100000
+}
+
+// fibHandler serves GET /fib?n=10&algo=big with the JSON -format json
+// prints, algo defaulting to -algo, or 400 Bad Request for an n or algo
+// compute refuses or an n beyond servedLimit. Under Go.js -serve registers
+// it with the host through nodehttp.
+func fibHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fib", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		n, err := strconv.Atoi(q.Get("n"))
+		if err != nil {
+			http.Error(w, "invalid n "+strconv.Quote(q.Get("n")), http.StatusBadRequest)
+			return
+		}
+		name := *algo
+		if q.Has("algo") {
+			name = q.Get("algo")
+		}
+		if limit := servedLimit(name); n > limit {
+			http.Error(w, fmt.Sprintf("n must be at most %d with algo %s, got %d", limit, name, n), http.StatusBadRequest)
+			return
+		}
+		res, err := compute(n, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	})
+	return mux
+}
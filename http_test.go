@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFibHandler(t *testing.T) {
+	cases := []struct {
+		method, target string
+		code           int
+		body           string
+	}{
+		{"GET", "/fib?n=10", http.StatusOK, `{"n":10,"algo":"recursive","fib":55}` + "\n"},
+		{"GET", "/fib?n=93&algo=big", http.StatusOK, `{"n":93,"algo":"big","fib":12200160415121876738}` + "\n"},
+		{"GET", "/fib?n=93&algo=iterative", http.StatusBadRequest, "fib(93): " + errOverflow.Error() + "\n"},
+		{"GET", "/fib?n=100000&algo=big", http.StatusOK, ""},
+		{"GET", "/fib?n=100001&algo=big", http.StatusBadRequest, "n must be at most 100000 with algo big, got 100001\n"},
+		{"GET", "/fib?n=41", http.StatusBadRequest, "n must be at most 40 with algo recursive, got 41\n"},
+		{"GET", "/fib?n=abc", http.StatusBadRequest, `invalid n "abc"` + "\n"},
+		{"GET", "/fib?n=-1", http.StatusBadRequest, "n must not be negative, got -1\n"},
+		{"GET", "/fib?n=1&algo=guess", http.StatusBadRequest, `unknown algorithm "guess"` + "\n"},
+		{"POST", "/fib?n=1", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+		{"GET", "/", http.StatusNotFound, "404 page not found\n"},
+	}
+	h := fibHandler()
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+		if rec.Code != tc.code || tc.body != "" && rec.Body.String() != tc.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tc.method, tc.target, rec.Code, rec.Body, tc.code, tc.body)
+		}
+	}
+}
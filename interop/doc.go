@@ -0,0 +1,12 @@
+// Package interop exports Go functions to the Go.js host and converts
+// failures crossing the boundary into typed errors in both directions.
+//
+// A panic inside a function registered with Export or wrapped with Func is
+// recovered and thrown into JS as a GoPanicError carrying the Go stack.
+// Exceptions thrown by JS code called through Call, Invoke or New are
+// returned as *JSError instead of panicking, as are rejections of promises
+// waited for with Await.
+//
+// Int64 and Uint64 convert JS numbers to 64-bit integers, refusing the ones
+// beyond Number.MAX_SAFE_INTEGER that a JS number can't hold exactly.
+package interop
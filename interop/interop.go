@@ -0,0 +1,157 @@
+//go:build js && wasm
+
+package interop
+
+import (
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strconv"
+	"syscall/js"
+)
+
+// maxSafeInteger is JS's Number.MAX_SAFE_INTEGER, the largest integer up
+// to which JS numbers hold every integer exactly.
+const maxSafeInteger = 1<<53 - 1
+
+// JSError is an exception thrown by JS code called from Go.
+type JSError struct {
+	Name    string
+	Message string
+	Stack   string
+
+	// Value is the thrown JS value.
+	Value js.Value
+}
+
+func (e *JSError) Error() string {
+	return e.Name + ": " + e.Message
+}
+
+// Export registers fn on the host's exports object under name.
+func Export(name string, fn func(this js.Value, args []js.Value) any) js.Func {
+	f := Func(fn)
+	js.Global().Get("exports").Set(name, f)
+	return f
+}
+
+// Func is like js.FuncOf, but a panic in fn is thrown into JS as a
+// GoPanicError instead of crashing the Go program.
+func Func(fn func(this js.Value, args []js.Value) any) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) (result any) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = panicError(r, debug.Stack())
+			}
+		}()
+		return fn(this, args)
+	})
+}
+
+// Call calls method m of v, returning a *JSError if it throws.
+func Call(v js.Value, m string, args ...any) (result js.Value, err error) {
+	defer recoverJSError(&err)
+	return v.Call(m, args...), nil
+}
+
+// Invoke calls v as a function, returning a *JSError if it throws.
+func Invoke(v js.Value, args ...any) (result js.Value, err error) {
+	defer recoverJSError(&err)
+	return v.Invoke(args...), nil
+}
+
+// New calls v as a constructor, returning a *JSError if it throws.
+func New(v js.Value, args ...any) (result js.Value, err error) {
+	defer recoverJSError(&err)
+	return v.New(args...), nil
+}
+
+// Await blocks until the promise p settles and returns its value, or a
+// *JSError for the value it was rejected with. Values that aren't promises
+// are returned as they are. Await must not be called on the goroutine of a
+// callback from JS, which can't return while it waits.
+func Await(p js.Value) (js.Value, error) {
+	type settled struct {
+		v   js.Value
+		err error
+	}
+	ch := make(chan settled, 1)
+	resolve := js.FuncOf(func(this js.Value, args []js.Value) any {
+		ch <- settled{v: args[0]}
+		return nil
+	})
+	defer resolve.Release()
+	reject := js.FuncOf(func(this js.Value, args []js.Value) any {
+		ch <- settled{err: newJSError(args[0])}
+		return nil
+	})
+	defer reject.Release()
+	js.Global().Get("Promise").Call("resolve", p).Call("then", resolve, reject)
+	s := <-ch
+	return s.v, s.err
+}
+
+// Int64 converts the JS number v to an int64 like v.Int, but panics if v
+// is beyond ±Number.MAX_SAFE_INTEGER, where JS may already have rounded it.
+func Int64(v js.Value) int64 {
+	f := v.Float()
+	if math.Abs(f) > maxSafeInteger || math.IsNaN(f) {
+		panic(fmt.Errorf("interop: %s is not a safe integer", strconv.FormatFloat(f, 'f', -1, 64)))
+	}
+	return int64(f)
+}
+
+// Uint64 is Int64 for unsigned integers, which also panics if v is
+// negative.
+func Uint64(v js.Value) uint64 {
+	f := v.Float()
+	if f > maxSafeInteger || f <= -1 || math.IsNaN(f) {
+		panic(fmt.Errorf("interop: %s is not a safe unsigned integer", strconv.FormatFloat(f, 'f', -1, 64)))
+	}
+	return uint64(f)
+}
+
+func recoverJSError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	jsErr, ok := r.(js.Error)
+	if !ok {
+		panic(r)
+	}
+	*err = newJSError(jsErr.Value)
+}
+
+func newJSError(v js.Value) *JSError {
+	e := &JSError{Name: "Error", Value: v}
+	if v.Type() != js.TypeObject {
+		e.Message = v.String()
+		return e
+	}
+	if name := v.Get("name"); name.Type() == js.TypeString {
+		e.Name = name.String()
+	}
+	if msg := v.Get("message"); msg.Type() == js.TypeString {
+		e.Message = msg.String()
+	}
+	if stack := v.Get("stack"); stack.Type() == js.TypeString {
+		e.Stack = stack.String()
+	}
+	return e
+}
+
+func panicError(r any, stack []byte) js.Value {
+	var msg string
+	switch r := r.(type) {
+	case *JSError:
+		msg = "panic: " + r.Error()
+	case js.Error:
+		msg = "panic: " + newJSError(r.Value).Error()
+	case error:
+		msg = "panic: " + r.Error()
+	default:
+		msg = fmt.Sprintf("panic: %v", r)
+	}
+	return js.Global().Get("GoPanicError").New(msg, string(stack))
+}
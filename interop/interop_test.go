@@ -0,0 +1,114 @@
+//go:build js && wasm
+
+package interop
+
+import (
+	"errors"
+	"strings"
+	"syscall/js"
+	"testing"
+)
+
+func TestCallReturnsJSError(t *testing.T) {
+	_, err := Call(js.Global().Get("Object"), "defineProperty", 1, "x", 2)
+	var jsErr *JSError
+	if !errors.As(err, &jsErr) {
+		t.Fatalf("err = %v, want *JSError", err)
+	}
+	if jsErr.Name != "TypeError" || jsErr.Message == "" || jsErr.Stack == "" {
+		t.Errorf("got %+v, want a TypeError with message and stack", jsErr)
+	}
+}
+
+func TestCallSucceeds(t *testing.T) {
+	v, err := Call(js.Global().Get("Math"), "max", 1, 3, 2)
+	if err != nil || v.Int() != 3 {
+		t.Errorf("Math.max(1, 3, 2) = %v, %v", v, err)
+	}
+}
+
+func TestNewReturnsJSError(t *testing.T) {
+	_, err := New(js.Global().Get("Uint8Array"), -1)
+	var jsErr *JSError
+	if !errors.As(err, &jsErr) || jsErr.Name != "RangeError" {
+		t.Errorf("new Uint8Array(-1) err = %v, want RangeError", err)
+	}
+}
+
+func TestFuncPanicThrowsGoPanicError(t *testing.T) {
+	f := Func(func(this js.Value, args []js.Value) any {
+		panic("boom")
+	})
+	defer f.Release()
+
+	_, err := Invoke(f.Value)
+	var jsErr *JSError
+	if !errors.As(err, &jsErr) {
+		t.Fatalf("err = %v, want *JSError", err)
+	}
+	if jsErr.Name != "GoPanicError" || jsErr.Message != "panic: boom" {
+		t.Errorf("got %s, want GoPanicError: panic: boom", jsErr)
+	}
+	if goStack := jsErr.Value.Get("goStack").String(); !strings.Contains(goStack, "TestFuncPanicThrowsGoPanicError") {
+		t.Errorf("goStack does not name the panicking test:\n%s", goStack)
+	}
+}
+
+func TestExport(t *testing.T) {
+	f := Export("double", func(this js.Value, args []js.Value) any {
+		return args[0].Int() * 2
+	})
+	defer f.Release()
+
+	v, err := Call(js.Global().Get("exports"), "double", 21)
+	if err != nil || v.Int() != 42 {
+		t.Errorf("exports.double(21) = %v, %v", v, err)
+	}
+}
+
+func TestAwait(t *testing.T) {
+	promise := js.Global().Get("Promise")
+	v, err := Await(promise.Call("resolve", 42))
+	if err != nil || v.Int() != 42 {
+		t.Errorf("Await(Promise.resolve(42)) = %v, %v", v, err)
+	}
+	v, err = Await(js.ValueOf("plain"))
+	if err != nil || v.String() != "plain" {
+		t.Errorf(`Await("plain") = %v, %v`, v, err)
+	}
+
+	_, err = Await(promise.Call("reject", js.Global().Get("Error").New("nope")))
+	var jsErr *JSError
+	if !errors.As(err, &jsErr) || jsErr.Message != "nope" {
+		t.Errorf("Await(Promise.reject(new Error('nope'))) err = %v, want *JSError nope", err)
+	}
+}
+
+func TestInt64(t *testing.T) {
+	const max = 1<<53 - 1
+	if got := Int64(js.ValueOf(-max)); got != -max {
+		t.Errorf("Int64(-max) = %d", got)
+	}
+	if got := Uint64(js.ValueOf(max)); got != max {
+		t.Errorf("Uint64(max) = %d", got)
+	}
+	for _, tt := range []struct {
+		name string
+		f    func(js.Value)
+		v    float64
+	}{
+		{"Int64", func(v js.Value) { Int64(v) }, max + 1},
+		{"Int64", func(v js.Value) { Int64(v) }, -max - 1},
+		{"Uint64", func(v js.Value) { Uint64(v) }, 1 << 60},
+		{"Uint64", func(v js.Value) { Uint64(v) }, -1},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s(%v) didn't panic", tt.name, tt.v)
+				}
+			}()
+			tt.f(js.ValueOf(tt.v))
+		}()
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the files in testdata/golden")
+
+// output is what a run of the program produced.
+type output struct {
+	stdout, stderr string
+	code           int
+}
+
+func run(t *testing.T, name string, args ...string) output {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("running %s: %v", name, err)
+	}
+	return output{stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()}
+}
+
+// build builds the package for goos/goarch into dir and returns the path.
+func build(t *testing.T, dir, goos, goarch, out string) string {
+	t.Helper()
+	out = filepath.Join(dir, out)
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building for %s/%s: %v\n%s", goos, goarch, err, b)
+	}
+	return out
+}
+
+// nodeRunner skips the test without node or with -short and returns node
+// and the path of testdata/run.js.
+func nodeRunner(t *testing.T) (node, runner string) {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+	if testing.Short() {
+		t.Skip("builds the program")
+	}
+	runner, err = filepath.Abs("testdata/run.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node, runner
+}
+
+// TestWasmMatchesNative runs the program natively and as js/wasm through
+// Go.js and expects the same output for every case.
+func TestWasmMatchesNative(t *testing.T) {
+	node, runner := nodeRunner(t)
+	dir := t.TempDir()
+	native := build(t, dir, "", "", "fib")
+	wasm := build(t, dir, "js", "wasm", "fib.wasm")
+
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{"default", nil},
+		{"zero", []string{"0"}},
+		{"one", []string{"1"}},
+		{"two", []string{"2"}},
+		{"negative", []string{"--", "-5"}},
+		{"non-numeric", []string{"abc"}},
+		{"empty", []string{""}},
+		{"float", []string{"1.5"}},
+		{"too large for int", []string{"99999999999999999999"}},
+		{"recursive", []string{"-algo", "recursive", "25"}},
+		{"iterative", []string{"-algo", "iterative", "90"}},
+		{"memo", []string{"-algo", "memo", "90"}},
+		{"matrix", []string{"-algo", "matrix", "90"}},
+		{"big", []string{"-algo", "big", "500"}},
+		{"largest int64", []string{"-algo", "iterative", "92"}},
+		{"overflow", []string{"-algo", "matrix", "93"}},
+		{"big past overflow", []string{"-algo", "big", "93"}},
+		{"unknown algorithm", []string{"-algo", "guess", "10"}},
+		{"json", []string{"-format", "json", "20"}},
+		{"json big", []string{"-format", "json", "-algo", "big", "300"}},
+		{"json alias", []string{"-json", "20"}},
+		{"unknown format", []string{"-format", "xml", "10"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := run(t, native, tc.args...)
+			got := run(t, node, append([]string{runner, wasm}, tc.args...)...)
+			if got != want {
+				t.Errorf("wasm = %+v\nnative = %+v", got, want)
+			}
+		})
+	}
+}
+
+// TestDefaultN checks that a missing, zero or non-numeric n means 10, as
+// it always has.
+func TestDefaultN(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the program")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	native := build(t, t.TempDir(), "", "", "fib")
+	for _, args := range [][]string{nil, {"0"}, {"abc"}, {""}, {"1.5"}} {
+		got := run(t, native, args...)
+		want := output{stdout: "fib(10) = 55\n"}
+		if got != want {
+			t.Errorf("%q: got %+v, want %+v", args, got, want)
+		}
+	}
+}
+
+// TestGolden runs the program as js/wasm in Go.js's deterministic mode,
+// where even the timing output is reproducible, and compares its stdout
+// with testdata/golden/<case>.golden. -update rewrites the files.
+func TestGolden(t *testing.T) {
+	node, runner := nodeRunner(t)
+	wasm := build(t, t.TempDir(), "js", "wasm", "fib.wasm")
+
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{"text", []string{"30"}},
+		{"json-timing", []string{"-format", "json", "-timing", "20"}},
+		{"json-timing-big", []string{"-format", "json", "-timing", "-algo", "big", "300"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := run(t, node, append([]string{runner, "--deterministic", wasm}, tc.args...)...)
+			if got.code != 0 {
+				t.Fatalf("exit code %d: %s", got.code, got.stderr)
+			}
+			golden := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got.stdout), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.stdout != string(want) {
+				t.Errorf("stdout = %q, want %q", got.stdout, want)
+			}
+		})
+	}
+}
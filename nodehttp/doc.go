@@ -0,0 +1,19 @@
+// Package nodehttp serves Go http.Handlers from Node's http server.
+//
+// GOOS=js programs can't listen on sockets, so the host does: Handle
+// registers a handler on the host's exports, and NodeHttp.js turns it into
+// a request listener for http.createServer that passes every request to
+// the handler.
+//
+//	nodehttp.Handle("http", mux)
+//
+//	const { createServer } = require('go-js/NodeHttp');
+//	createServer(go, 'http').listen(8080);
+//
+// Request headers, bodies and trailers are streamed from Node as the
+// handler reads them, and the response is written to Node as the handler
+// writes it, waiting for Node to drain its buffer when it is full.
+// Response trailers are declared as with net/http, through the Trailer
+// header or http.TrailerPrefix. The request's context is canceled when the
+// client goes away or the handler returns.
+package nodehttp
@@ -0,0 +1,274 @@
+//go:build js && wasm
+
+package nodehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"go-to-js/interop"
+)
+
+// errClosed is returned by writes to a response whose connection is gone.
+var errClosed = errors.New("nodehttp: connection closed")
+
+// Handle registers h on the host's exports under name. Each request the
+// host passes in is served on its own goroutine.
+func Handle(name string, h http.Handler) js.Func {
+	return interop.Export(name, func(this js.Value, args []js.Value) any {
+		go serve(h, args[0])
+		return nil
+	})
+}
+
+// serve runs h for the request NodeHttp.js describes with req.
+func serve(h http.Handler, req js.Value) {
+	w := &response{req: req, header: make(http.Header)}
+	r, err := newRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		w.finish()
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		// resolves once the response is closed, to true if that was
+		// before it finished
+		if aborted, err := interop.Await(req.Get("closed")); err == nil && aborted.Bool() {
+			cancel()
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			if p != http.ErrAbortHandler {
+				log.Printf("http: panic serving %s: %v\n%s", r.RemoteAddr, p, debug.Stack())
+			}
+			if w.wroteHeader {
+				req.Call("destroy")
+				return
+			}
+			w.header = make(http.Header)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		w.finish()
+	}()
+	h.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func newRequest(req js.Value) (*http.Request, error) {
+	requestURI := req.Get("url").String()
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return nil, err
+	}
+	proto := req.Get("proto").String()
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		return nil, fmt.Errorf("malformed HTTP version %q", proto)
+	}
+	r := &http.Request{
+		Method:     req.Get("method").String(),
+		URL:        u,
+		Proto:      proto,
+		ProtoMajor: major,
+		ProtoMinor: minor,
+		Header:     headers(req.Get("headers")),
+		RemoteAddr: req.Get("remoteAddr").String(),
+		RequestURI: requestURI,
+	}
+	r.Host = r.Header.Get("Host")
+	delete(r.Header, "Host")
+
+	switch {
+	case r.Header.Get("Content-Length") != "":
+		r.ContentLength, err = strconv.ParseInt(r.Header.Get("Content-Length"), 10, 64)
+		if err != nil || r.ContentLength < 0 {
+			return nil, fmt.Errorf("bad Content-Length %q", r.Header.Get("Content-Length"))
+		}
+	case strings.EqualFold(r.Header.Get("Transfer-Encoding"), "chunked"):
+		r.ContentLength = -1
+		r.TransferEncoding = []string{"chunked"}
+		delete(r.Header, "Transfer-Encoding")
+	}
+	if r.ContentLength == 0 {
+		r.Body = http.NoBody
+	} else {
+		r.Body = &body{req: req, trailer: declared(r.Header)}
+		r.Trailer = r.Body.(*body).trailer
+	}
+	return r, nil
+}
+
+// headers converts Node's raw headers, a flat list of names and values,
+// into an http.Header.
+func headers(raw js.Value) http.Header {
+	h := make(http.Header)
+	for i := 0; i+1 < raw.Length(); i += 2 {
+		h.Add(raw.Index(i).String(), raw.Index(i+1).String())
+	}
+	return h
+}
+
+// declared returns the trailers announced in the Trailer header with no
+// values yet, or nil if there are none.
+func declared(h http.Header) http.Header {
+	var trailer http.Header
+	for _, v := range h.Values("Trailer") {
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				if trailer == nil {
+					trailer = make(http.Header)
+				}
+				trailer[http.CanonicalHeaderKey(key)] = nil
+			}
+		}
+	}
+	return trailer
+}
+
+// body reads a request body chunk by chunk from Node, filling in the
+// request's trailers once it has been read to the end.
+type body struct {
+	req     js.Value
+	chunk   js.Value
+	off, n  int
+	eof     bool
+	trailer http.Header
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for b.off == b.n {
+		if b.eof {
+			return 0, io.EOF
+		}
+		chunk, err := interop.Await(b.req.Call("read"))
+		if err != nil {
+			return 0, err
+		}
+		if chunk.IsNull() {
+			b.eof = true
+			if b.trailer != nil {
+				for key, values := range headers(b.req.Call("trailers")) {
+					b.trailer[key] = values
+				}
+			}
+			return 0, io.EOF
+		}
+		b.chunk, b.off, b.n = chunk, 0, chunk.Length()
+	}
+	n := js.CopyBytesToGo(p, b.chunk.Call("subarray", b.off))
+	b.off += n
+	return n, nil
+}
+
+func (b *body) Close() error {
+	return nil
+}
+
+// response writes to Node's http.ServerResponse through NodeHttp.js.
+type response struct {
+	req         js.Value
+	header      http.Header
+	wroteHeader bool
+	err         error
+}
+
+func (w *response) Header() http.Header {
+	return w.header
+}
+
+func (w *response) WriteHeader(code int) {
+	if w.wroteHeader {
+		log.Printf("http: superfluous response.WriteHeader call with %d", code)
+		return
+	}
+	if code < 100 || code > 999 {
+		panic(fmt.Sprintf("invalid WriteHeader code %v", code))
+	}
+	w.wroteHeader = true
+	var raw []any
+	for key, values := range w.header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+		for _, v := range values {
+			raw = append(raw, key, v)
+		}
+	}
+	w.req.Call("writeHead", code, raw)
+}
+
+func (w *response) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.header.Get("Content-Type") == "" && w.header.Get("Transfer-Encoding") == "" {
+			w.header.Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	chunk := js.Global().Get("Uint8Array").New(len(p))
+	js.CopyBytesToJS(chunk, p)
+	// null when Node took the chunk, otherwise a promise resolving once
+	// its buffer drained, to true if the connection closed instead
+	if drained := w.req.Call("write", chunk); !drained.IsNull() {
+		closed, err := interop.Await(drained)
+		if err != nil {
+			w.err = err
+		} else if closed.Bool() {
+			w.err = errClosed
+		}
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// Flush sends the header if it hasn't been yet. Written data isn't
+// buffered on the Go side.
+func (w *response) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.req.Call("flush")
+}
+
+// finish ends the response with the trailers the handler set.
+func (w *response) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	var raw []any
+	for key, values := range w.header {
+		if name, ok := strings.CutPrefix(key, http.TrailerPrefix); ok {
+			for _, v := range values {
+				raw = append(raw, name, v)
+			}
+		}
+	}
+	for key := range declared(w.header) {
+		for _, v := range w.header.Values(key) {
+			raw = append(raw, key, v)
+		}
+	}
+	w.req.Call("end", raw)
+}
@@ -0,0 +1,58 @@
+// Command echo serves the handlers test/http.js checks nodehttp with.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"go-to-js/nodehttp"
+)
+
+func main() {
+	mux := http.NewServeMux()
+	// echoes the body in the chunks it arrives in, with its length and
+	// checksum and the request's X-Sent trailer as trailers
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Length, X-Sum")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header()["X-Method"] = []string{r.Method}
+		if s := r.URL.Query().Get("status"); s != "" {
+			code, _ := strconv.Atoi(s)
+			w.WriteHeader(code)
+		}
+		sum := sha256.New()
+		n, err := io.Copy(w, io.TeeReader(r.Body, sum))
+		if err != nil {
+			panic(err)
+		}
+		w.Header().Set("X-Length", strconv.FormatInt(n, 10))
+		w.Header().Set("X-Sum", hex.EncodeToString(sum.Sum(nil)))
+		w.Header().Set(http.TrailerPrefix+"X-Sent", r.Trailer.Get("X-Sent"))
+	})
+	// writes n MB, more than Node buffers
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		mb := []byte(strings.Repeat("x", 1<<20))
+		for i := 0; i < n; i++ {
+			if _, err := w.Write(mb); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	// waits for the client to go away and records that on exports.canceled
+	mux.HandleFunc("/wait", func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		js.Global().Get("exports").Set("canceled", r.Context().Err().Error())
+	})
+	nodehttp.Handle("http", mux)
+	select {}
+}
@@ -0,0 +1,60 @@
+const crypto = require('crypto');
+const fs = require('fs');
+const inspector = require('inspector');
+const path = require('path');
+const { Duplex } = require('stream');
+
+// The Node platform of the Go class. A platform provides what Go.js needs
+// from its host: a monotonic clock in ms, random bytes, synchronous writes
+// to stdout and stderr, loading wasm from a path or URL as bytes or a fetch
+// Response, writing files (used for traces) and the fs, process and path
+// globals the Go runtime calls into. Duplex, where the host has Node's
+// streams, is what go.createStream returns, and startProfiler, where it has
+// V8's CPU profiler, what Profiler samples with.
+module.exports = {
+  name: 'node',
+
+  now() {
+    const [sec, nsec] = process.hrtime();
+    return sec * 1000 + nsec / 1000000;
+  },
+
+  randomFill(buf) {
+    crypto.randomFillSync(buf);
+  },
+
+  writeSync(fd, buf) {
+    fs.writeSync(fd, buf);
+  },
+
+  loadFile(file) {
+    return fs.promises.readFile(file);
+  },
+
+  writeFile(file, data) {
+    fs.writeFileSync(file, data);
+  },
+
+  // startProfiler starts sampling every interval microseconds and
+  // resolves to a function stopping it, which resolves to the V8 CPU
+  // profile
+  async startProfiler(interval) {
+    const session = new inspector.Session();
+    session.connect();
+    const post = (method, params) => new Promise((resolve, reject) => {
+      session.post(method, params, (err, result) => (err ? reject(err) : resolve(result)));
+    });
+    await post('Profiler.enable');
+    await post('Profiler.setSamplingInterval', { interval });
+    await post('Profiler.start');
+    return async () => {
+      const { profile } = await post('Profiler.stop');
+      session.disconnect();
+      return profile;
+    };
+  },
+
+  globals: { fs, process, path },
+
+  Duplex,
+};
@@ -0,0 +1,113 @@
+// The browser and Deno platform of the Go class, see platform/node.js for
+// what a platform provides. Browsers have no stdio, so output is line
+// buffered onto the console and the fs, process and path globals are the
+// same stubs Go's own wasm_exec.js installs. Deno writes to its real stdio
+// and can read wasm files from disk.
+const isDeno = typeof Deno !== 'undefined';
+
+const enosys = () => {
+  const err = new Error('not implemented');
+  err.code = 'ENOSYS';
+  return err;
+};
+
+// partial lines written to stdout and stderr, printed once complete
+const pending = { 1: '', 2: '' };
+const decoder = new TextDecoder('utf-8');
+
+const writeSync = (fd, buf) => {
+  if (isDeno && (fd === 1 || fd === 2)) {
+    const out = fd === 1 ? Deno.stdout : Deno.stderr;
+    for (let off = 0; off < buf.length;) {
+      off += out.writeSync(buf.subarray(off));
+    }
+    return buf.length;
+  }
+  if (pending[fd] === undefined) {
+    throw enosys();
+  }
+  pending[fd] += decoder.decode(buf, { stream: true });
+  const nl = pending[fd].lastIndexOf('\n');
+  if (nl !== -1) {
+    (fd === 1 ? console.log : console.error)(pending[fd].substring(0, nl));
+    pending[fd] = pending[fd].substring(nl + 1);
+  }
+  return buf.length;
+};
+
+const fs = {
+  constants: { O_WRONLY: -1, O_RDWR: -1, O_CREAT: -1, O_TRUNC: -1, O_APPEND: -1, O_EXCL: -1, O_DIRECTORY: -1 },
+  writeSync,
+  write(fd, buf, offset, length, position, callback) {
+    if (offset !== 0 || length !== buf.length || position !== null) {
+      callback(enosys());
+      return;
+    }
+    try {
+      callback(null, writeSync(fd, buf));
+    } catch (err) {
+      callback(err);
+    }
+  },
+};
+['chmod', 'chown', 'close', 'fchmod', 'fchown', 'fstat', 'fsync', 'ftruncate', 'lchown', 'link', 'lstat', 'mkdir',
+  'open', 'read', 'readdir', 'readlink', 'rename', 'rmdir', 'stat', 'symlink', 'truncate', 'unlink', 'utimes'].forEach((name) => {
+  fs[name] = (...args) => args[args.length - 1](enosys());
+});
+
+const process = {
+  getuid() { return -1; },
+  getgid() { return -1; },
+  geteuid() { return -1; },
+  getegid() { return -1; },
+  getgroups() { throw enosys(); },
+  pid: -1,
+  ppid: -1,
+  umask() { throw enosys(); },
+  cwd() { throw enosys(); },
+  chdir() { throw enosys(); },
+};
+
+const path = {
+  resolve(...paths) {
+    return paths.join('/');
+  },
+};
+
+// browsers fetch everything, Deno only URLs and reads other paths from disk
+const fetched = (src) => !isDeno || src instanceof URL || /^[a-z][a-z0-9+.-]+:/i.test(src);
+
+export default {
+  name: isDeno ? 'deno' : 'web',
+
+  now() {
+    return performance.now();
+  },
+
+  randomFill(buf) {
+    // getRandomValues fills at most 65536 bytes per call
+    for (let off = 0; off < buf.length; off += 65536) {
+      crypto.getRandomValues(buf.subarray(off, off + 65536));
+    }
+  },
+
+  writeSync,
+
+  // a Response lets Go.js compile the module while it downloads
+  loadFile(src) {
+    return fetched(src) ? fetch(src) : Deno.readFile(src);
+  },
+
+  writeFile(file, data) {
+    if (!isDeno) {
+      throw new Error(`cannot write ${file}: browsers have no file system`);
+    }
+    if (typeof data === 'string') {
+      Deno.writeTextFileSync(file, data);
+    } else {
+      Deno.writeFileSync(file, data);
+    }
+  },
+
+  globals: { fs, process, path },
+};
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"go-to-js/events"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// maxRange is the most results a fibRange call returns.
+const maxRange = 10000
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	// ID is nil for notifications, which get no response.
+	ID json.RawMessage `json:"id"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func invalidParams(format string, args ...any) error {
+	return &rpcError{rpcInvalidParams, fmt.Sprintf(format, args...)}
+}
+
+// rpcMethods are the methods -rpc serves. Params can be given by position
+// or by name:
+//
+//	fib       [n, algo] or {"n", "algo"}          fib(n)
+//	fibRange  [from, to, algo] or {"from", ...}   fib(from) to fib(to)
+//	lucas     [n, algo] or {"n", "algo"}          the nth Lucas number
+//
+// algo defaults to -algo. Numbers are returned as JSON numbers while a JS
+// number holds them exactly and as decimal strings beyond that. Under
+// Go.js, fibRange emits its progress and each number as it is computed as
+// "progress" and "result" events.
+var rpcMethods = map[string]func(params json.RawMessage) (any, error){
+	"fib": func(params json.RawMessage) (any, error) {
+		n, f, err := nAndAlgorithm(params)
+		if err != nil {
+			return nil, err
+		}
+		v, err := f(n)
+		if err != nil {
+			return nil, err
+		}
+		return exactNumber(v), nil
+	},
+	"fibRange": func(params json.RawMessage) (any, error) {
+		var from, to *int
+		name := *algo
+		if err := decodeParams(params, []string{"from", "to", "algo"}, &from, &to, &name); err != nil {
+			return nil, err
+		}
+		if from == nil || to == nil {
+			return nil, invalidParams("from and to are required")
+		}
+		if *from < 0 || *to < *from {
+			return nil, invalidParams("want 0 <= from <= to, got from %d, to %d", *from, *to)
+		}
+		if *to-*from >= maxRange {
+			return nil, invalidParams("at most %d results per call", maxRange)
+		}
+		f, ok := algorithms[name]
+		if !ok {
+			return nil, invalidParams("unknown algorithm %q", name)
+		}
+		total := *to - *from + 1
+		progress := events.NewTracker(fmt.Sprintf("fibRange(%d, %d)", *from, *to), total)
+		partial := events.Listening("result")
+		results := make([]any, 0, total)
+		for n := *from; n <= *to; n++ {
+			progress.Set(n - *from)
+			v, err := f(n)
+			if err != nil {
+				return nil, fmt.Errorf("fib(%d): %w", n, err)
+			}
+			results = append(results, exactNumber(v))
+			if partial {
+				events.Emit("result", partialResult{N: n, Fib: exactNumber(v)})
+			}
+		}
+		progress.Set(total)
+		return results, nil
+	},
+	"lucas": func(params json.RawMessage) (any, error) {
+		n, f, err := nAndAlgorithm(params)
+		if err != nil {
+			return nil, err
+		}
+		v, err := lucas(f, n)
+		if err != nil {
+			return nil, err
+		}
+		return exactNumber(v), nil
+	},
+}
+
+// partialResult is the payload of the "result" events fibRange emits for
+// each number as it is computed.
+type partialResult struct {
+	N   int `json:"n"`
+	Fib any `json:"fib"`
+}
+
+func nAndAlgorithm(params json.RawMessage) (int, func(int) (*big.Int, error), error) {
+	var n *int
+	name := *algo
+	if err := decodeParams(params, []string{"n", "algo"}, &n, &name); err != nil {
+		return 0, nil, err
+	}
+	if n == nil {
+		return 0, nil, invalidParams("n is required")
+	}
+	if *n < 0 {
+		return 0, nil, invalidParams("n must not be negative, got %d", *n)
+	}
+	f, ok := algorithms[name]
+	if !ok {
+		return 0, nil, invalidParams("unknown algorithm %q", name)
+	}
+	return *n, f, nil
+}
+
+// decodeParams decodes params given by position or by the given names into
+// dst, leaving those not given as they are.
+func decodeParams(params json.RawMessage, names []string, dst ...any) error {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil
+	}
+	byName := make(map[string]json.RawMessage)
+	switch params[0] {
+	case '[':
+		var list []json.RawMessage
+		if err := json.Unmarshal(params, &list); err != nil {
+			return invalidParams("%v", err)
+		}
+		if len(list) > len(names) {
+			return invalidParams("at most %d params, got %d", len(names), len(list))
+		}
+		for i, v := range list {
+			byName[names[i]] = v
+		}
+	case '{':
+		if err := json.Unmarshal(params, &byName); err != nil {
+			return invalidParams("%v", err)
+		}
+	default:
+		return invalidParams("params must be an array or an object")
+	}
+	for i, name := range names {
+		v, ok := byName[name]
+		if !ok {
+			continue
+		}
+		delete(byName, name)
+		if err := json.Unmarshal(v, dst[i]); err != nil {
+			return invalidParams("%s: %v", name, err)
+		}
+	}
+	for name := range byName {
+		return invalidParams("unknown param %q", name)
+	}
+	return nil
+}
+
+// exactNumber returns v as an int64 if a JS number holds it exactly and as
+// a decimal string otherwise.
+func exactNumber(v *big.Int) any {
+	if v.IsInt64() && v.Int64() <= 1<<53 {
+		return v.Int64()
+	}
+	return v.String()
+}
+
+// serveRPC answers the JSON-RPC 2.0 requests read from r, one request or
+// batch per line, writing a line to w for each response. Requests are
+// handled concurrently, so responses can come out of order. It returns
+// once r is at its end and every request has been answered.
+func serveRPC(r io.Reader, w io.Writer) error {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		enc = json.NewEncoder(w)
+	)
+	enc.SetEscapeHTML(false)
+	respond := func(v any) {
+		mu.Lock()
+		defer mu.Unlock()
+		// a failed write is the client's loss, keep serving the others
+		enc.Encode(v)
+	}
+
+	in := bufio.NewReader(r)
+	for {
+		line, err := in.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res := handleRPC(line); res != nil {
+					respond(res)
+				}
+			}()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// handleRPC answers a line holding a request or a batch of them, returning
+// nil if nothing is to be sent back.
+func handleRPC(line []byte) any {
+	if line[0] != '[' {
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			return errorResponse(nil, decodeError(line, err))
+		}
+		if res := call(req); res != nil {
+			return res
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(line, &batch); err != nil {
+		return errorResponse(nil, decodeError(line, err))
+	}
+	if len(batch) == 0 {
+		return errorResponse(nil, &rpcError{rpcInvalidRequest, "empty batch"})
+	}
+	var responses []*rpcResponse
+	for _, raw := range batch {
+		var req rpcRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			responses = append(responses, errorResponse(nil, &rpcError{rpcInvalidRequest, err.Error()}))
+			continue
+		}
+		if res := call(req); res != nil {
+			responses = append(responses, res)
+		}
+	}
+	if responses == nil {
+		return nil
+	}
+	return responses
+}
+
+// call runs a single request, returning nil for notifications.
+func call(req rpcRequest) *rpcResponse {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &rpcError{rpcInvalidRequest, `want "jsonrpc": "2.0" and a method`})
+	}
+	method, ok := rpcMethods[req.Method]
+	var (
+		result any
+		err    error
+	)
+	if ok {
+		result, err = method(req.Params)
+	} else {
+		err = &rpcError{rpcMethodNotFound, "method not found: " + req.Method}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{rpcServerError, err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+	return &rpcResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+// decodeError tells JSON that doesn't parse from JSON that isn't a request.
+func decodeError(data []byte, err error) *rpcError {
+	if json.Valid(data) {
+		return &rpcError{rpcInvalidRequest, err.Error()}
+	}
+	return &rpcError{rpcParseError, err.Error()}
+}
+
+func errorResponse(id json.RawMessage, err *rpcError) *rpcResponse {
+	return &rpcResponse{JSONRPC: "2.0", Error: err, ID: id}
+}
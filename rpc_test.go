@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestServeRPC(t *testing.T) {
+	cases := []struct {
+		name, request, response string
+	}{
+		{"fib", `{"jsonrpc":"2.0","method":"fib","params":[10],"id":1}`, `{"jsonrpc":"2.0","result":55,"id":1}`},
+		{"named params", `{"jsonrpc":"2.0","method":"fib","params":{"n":100,"algo":"big"},"id":"a"}`, `{"jsonrpc":"2.0","result":"354224848179261915075","id":"a"}`},
+		{"fibRange", `{"jsonrpc":"2.0","method":"fibRange","params":[5,8,"matrix"],"id":2}`, `{"jsonrpc":"2.0","result":[5,8,13,21],"id":2}`},
+		{"lucas", `{"jsonrpc":"2.0","method":"lucas","params":{"n":10},"id":3}`, `{"jsonrpc":"2.0","result":123,"id":3}`},
+		{"null id", `{"jsonrpc":"2.0","method":"fib","params":[3],"id":null}`, `{"jsonrpc":"2.0","result":2,"id":null}`},
+		{"notification", `{"jsonrpc":"2.0","method":"fib","params":[3]}`, ``},
+		{"overflow", `{"jsonrpc":"2.0","method":"fib","params":[93,"iterative"],"id":4}`, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"result overflows int64, use -algo big"},"id":4}`},
+		{"missing n", `{"jsonrpc":"2.0","method":"lucas","params":[],"id":5}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"n is required"},"id":5}`},
+		{"negative n", `{"jsonrpc":"2.0","method":"fib","params":[-1],"id":6}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"n must not be negative, got -1"},"id":6}`},
+		{"unknown param", `{"jsonrpc":"2.0","method":"fib","params":{"m":1},"id":7}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"unknown param \"m\""},"id":7}`},
+		{"bad range", `{"jsonrpc":"2.0","method":"fibRange","params":[3,1],"id":8}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"want 0 <= from <= to, got from 3, to 1"},"id":8}`},
+		{"unknown method", `{"jsonrpc":"2.0","method":"fob","id":9}`, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: fob"},"id":9}`},
+		{"not 2.0", `{"method":"fib","params":[1],"id":10}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"want \"jsonrpc\": \"2.0\" and a method"},"id":10}`},
+		{"parse error", `{"jsonrpc"`, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"unexpected end of JSON input"},"id":null}`},
+		{"empty batch", `[]`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`},
+		{"batch", `[{"jsonrpc":"2.0","method":"fib","params":[5],"id":11},{"jsonrpc":"2.0","method":"fib","params":[6]},{"jsonrpc":"2.0","method":"lucas","params":[0],"id":12}]`, `[{"jsonrpc":"2.0","result":5,"id":11},{"jsonrpc":"2.0","result":2,"id":12}]`},
+		{"notification batch", `[{"jsonrpc":"2.0","method":"fib","params":[5]}]`, ``},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := serveRPC(strings.NewReader(tc.request+"\n"), &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSuffix(out.String(), "\n"); got != tc.response {
+				t.Errorf("response = %s\nwant %s", got, tc.response)
+			}
+		})
+	}
+
+	// all at once, without a trailing newline, answered in any order
+	var requests, want []string
+	for _, tc := range cases {
+		requests = append(requests, tc.request)
+		if tc.response != "" {
+			want = append(want, tc.response)
+		}
+	}
+	var out bytes.Buffer
+	if err := serveRPC(strings.NewReader(strings.Join(requests, "\n\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("responses =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,113 @@
+// Checks deterministic runs: the virtual clock's timers, moved by itself or
+// by advance(), programs sleeping on it, and seeded random data, by
+// running testdata/clock, which prints the time around sleeps and random
+// bytes.
+//
+//   npm run test:clock
+const assert = require('assert');
+const { execFileSync } = require('child_process');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+const hour = 3600 * 1000;
+
+const idle = () => new Promise(resolve => setTimeout(resolve, 10));
+
+const timers = async () => {
+  const clock = new Go.VirtualClock({ time: 1000, autoAdvance: false });
+  const fired = [];
+  clock.setTimeout(() => fired.push(['b', clock.now]), 20);
+  clock.setTimeout(() => fired.push(['a', clock.now]), 10);
+  const cleared = clock.setTimeout(() => fired.push(['cleared', clock.now]), 15);
+  clock.setTimeout(() => fired.push(['b2', clock.now]), 20);
+  clock.clearTimeout(cleared);
+  assert.strictEqual(clock.now, 0);
+  assert.strictEqual(clock.wallTime, 1000);
+
+  clock.advance(5);
+  assert.deepStrictEqual(fired, []);
+  clock.advance(25);
+  assert.deepStrictEqual(fired, [['a', 10], ['b', 20], ['b2', 20]]);
+  assert.strictEqual(clock.now, 30);
+  assert.strictEqual(clock.wallTime, 1030);
+
+  // timers set while advancing fire too if they're due on the way
+  clock.setTimeout(() => clock.setTimeout(() => fired.push(['nested', clock.now]), 5), 5);
+  clock.advance(10);
+  assert.deepStrictEqual(fired.pop(), ['nested', 40]);
+  assert.strictEqual(clock.now, 40);
+
+  // negative delays are due now
+  clock.setTimeout(() => fired.push(['now', clock.now]), -1);
+  clock.advance(0);
+  assert.deepStrictEqual(fired.pop(), ['now', 40]);
+};
+
+// with autoAdvance the clock jumps to the next timer once JS is idle
+const autoAdvance = async () => {
+  const clock = new Go.VirtualClock();
+  const fired = [];
+  clock.setTimeout(() => fired.push(clock.now), hour);
+  clock.setTimeout(() => fired.push(clock.now), 24 * hour);
+  assert.deepStrictEqual(fired, []);
+  await idle();
+  assert.deepStrictEqual(fired, [hour, 24 * hour]);
+  assert.strictEqual(clock.wallTime, Date.UTC(2000, 0, 2));
+};
+
+const sleepAuto = async (wasm) => {
+  const start = Date.now();
+  const { stdout } = await new Go(wasm, { deterministic: true, capture: true }).run('1h', '24h');
+  assert.deepStrictEqual(stdout.split('\n').slice(0, 3), [
+    '2000-01-01T00:00:00Z',
+    '2000-01-01T01:00:00Z',
+    '2000-01-02T01:00:00Z',
+  ]);
+  assert(Date.now() - start < 10000, 'slept for real');
+};
+
+const sleepManual = async (wasm) => {
+  const lines = [];
+  const go = new Go(wasm, {
+    deterministic: { autoAdvance: false, time: Date.UTC(2024, 1, 29) },
+    stdout: chunk => lines.push(...Buffer.from(chunk).toString().split('\n').filter(Boolean)),
+  });
+  const done = go.run('1h');
+  // Go returns to JS once it sleeps
+  await go.waitReady();
+  await idle();
+  assert.deepStrictEqual(lines, ['2024-02-29T00:00:00Z']);
+  go.clock.advance(hour - 1);
+  await idle();
+  assert.strictEqual(lines.length, 1);
+  assert(!go.exited);
+  go.clock.advance(1);
+  const { code } = await done;
+  assert.strictEqual(code, 0);
+  assert.strictEqual(lines[1], '2024-02-29T01:00:00Z');
+};
+
+const seeded = async (wasm) => {
+  const run = async (seed) => {
+    const { stdout } = await new Go(wasm, { deterministic: { seed }, capture: true }).run();
+    return stdout.split('\n')[1];
+  };
+  const first = await run(7);
+  assert.match(first, /^[0-9a-f]{16}$/);
+  assert.strictEqual(await run(7), first);
+  assert.notStrictEqual(await run(8), first);
+};
+
+runTests([timers, autoAdvance, sleepAuto, sleepManual, seeded], {
+  tmp: 'clock',
+  setup: (dir) => {
+    const wasm = path.join(dir, 'clock.wasm');
+    execFileSync('go', ['build', '-o', wasm, './testdata/clock'], {
+      cwd: root,
+      env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+    });
+    return wasm;
+  },
+});
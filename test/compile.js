@@ -0,0 +1,93 @@
+// Compiles main.wasm from each kind of source Go.compile and the Go
+// constructor accept, and checks the ones it refuses.
+//
+//   npm run build:go && npm run test:compile
+const assert = require('assert');
+const fs = require('fs');
+const http = require('http');
+const path = require('path');
+const { Readable } = require('stream');
+const { pathToFileURL } = require('url');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const wasm = path.join(__dirname, '..', 'main.wasm');
+
+const compiled = (module) => {
+  assert(module instanceof WebAssembly.Module);
+  const exports = WebAssembly.Module.exports(module).map(e => e.name);
+  assert(exports.includes('run') && exports.includes('mem'), exports.join(', '));
+  return module;
+};
+
+const runs = async (source) => {
+  const { code, stdout } = await new Go(source, { capture: true }).run('10');
+  assert.strictEqual(code, 0);
+  assert.strictEqual(stdout, 'fib(10) = 55\n');
+};
+
+const buffer = async () => {
+  const bytes = fs.readFileSync(wasm);
+  compiled(await Go.compile(bytes));
+  compiled(await Go.compile(bytes.buffer.slice(bytes.byteOffset, bytes.byteOffset + bytes.length)));
+  compiled(await Go.compile(new DataView(bytes.buffer, bytes.byteOffset, bytes.length)));
+  await runs(bytes);
+};
+
+const wasmModule = async () => {
+  const mod = new WebAssembly.Module(fs.readFileSync(wasm));
+  assert.strictEqual(await Go.compile(mod), mod);
+  assert.strictEqual(await Go.compile(Promise.resolve(mod)), mod);
+  await runs(mod);
+};
+
+const nodeStream = async () => {
+  compiled(await Go.compile(fs.createReadStream(wasm, { highWaterMark: 4096 })));
+  await runs(fs.createReadStream(wasm));
+  await assert.rejects(Go.compile(fs.createReadStream(wasm, { encoding: 'latin1' })), /must not have an encoding/);
+};
+
+const webStream = async () => {
+  compiled(await Go.compile(Readable.toWeb(fs.createReadStream(wasm))));
+};
+
+const response = async () => {
+  const bytes = fs.readFileSync(wasm);
+  // compiled while downloading, or from the whole body without the wasm type
+  compiled(await Go.compile(new Response(bytes, { headers: { 'Content-Type': 'application/wasm' } })));
+  compiled(await Go.compile(new Response(bytes, { headers: { 'Content-Type': 'application/octet-stream' } })));
+  await runs(new Response(bytes, { headers: { 'Content-Type': 'application/wasm' } }));
+  await assert.rejects(Go.compile(new Response('gone', { status: 404, statusText: 'Not Found' })), /404 Not Found/);
+};
+
+const url = async () => {
+  compiled(await Go.compile(pathToFileURL(wasm)));
+  compiled(await Go.compile(wasm));
+
+  // http(s) URLs are fetched
+  const server = http.createServer((req, res) => {
+    if (req.url !== '/main.wasm') {
+      res.writeHead(404).end();
+      return;
+    }
+    res.writeHead(200, { 'Content-Type': 'application/wasm' });
+    fs.createReadStream(wasm).pipe(res);
+  });
+  await new Promise(resolve => server.listen(0, '127.0.0.1', resolve));
+  try {
+    const base = `http://127.0.0.1:${server.address().port}`;
+    compiled(await Go.compile(new URL('/main.wasm', base)));
+    await runs(`${base}/main.wasm`);
+    await assert.rejects(Go.compile(`${base}/missing.wasm`), /404/);
+  } finally {
+    server.close();
+  }
+};
+
+const refused = async () => {
+  await assert.rejects(Go.compile({}), /cannot load wasm from \[object Object\]/);
+  await assert.rejects(Go.compile(42), /cannot load wasm from \[object Number\]/);
+  await assert.rejects(Go.compile(Buffer.from('not wasm')), WebAssembly.CompileError);
+};
+
+runTests([buffer, wasmModule, nodeStream, webStream, response, url, refused]);
@@ -0,0 +1,84 @@
+// Listens to the events main.wasm emits while it computes: progress of a
+// large fib and of fibRange, and fibRange's partial results.
+//
+//   npm run build:go && npm run test:events
+const assert = require('assert');
+const { EventEmitter } = require('events');
+const path = require('path');
+const Go = require('../Go');
+const RpcClient = require('../RpcClient');
+const runTests = require('./run');
+
+const wasm = path.join(__dirname, '..', 'main.wasm');
+
+const fib = (n) => {
+  let [a, b] = [0n, 1n];
+  for (let i = 0; i < n; i++) {
+    [a, b] = [b, a + b];
+  }
+  return a;
+};
+
+const progress = async () => {
+  const go = new Go(wasm, { capture: true });
+  assert(go instanceof EventEmitter);
+  const events = [];
+  go.on('progress', p => events.push(p));
+  let exitCode;
+  go.on('exit', (code) => {
+    exitCode = code;
+  });
+  const { code, stdout } = await go.run('-algo', 'big', '20000');
+  assert.strictEqual(code, 0);
+  assert.strictEqual(exitCode, 0);
+  assert.strictEqual(stdout, `fib(20000) = ${fib(20000)}
+`);
+  assert.strictEqual(events.length, 101);
+  events.forEach((p, i) => {
+    assert.strictEqual(p.task, 'fib(20000)');
+    assert.strictEqual(p.total, 20000);
+    assert.strictEqual(p.percent, i);
+  });
+  assert.strictEqual(events[100].done, 20000);
+};
+
+// events arrive while the call is running, before its response
+const partialResults = async () => {
+  const client = RpcClient.go(wasm);
+  const seen = [];
+  client.go.on('result', r => seen.push(`result ${r.n} ${r.fib}`));
+  client.go.on('progress', p => seen.push(`progress ${p.percent}`));
+  const pending = client.call('fibRange', [0, 199, 'big']).then((results) => {
+    seen.push('response');
+    return results;
+  });
+  const results = await pending;
+  assert.strictEqual(seen[0], 'progress 0');
+  assert.strictEqual(seen[1], 'result 0 0');
+  assert.strictEqual(seen[seen.length - 2], 'progress 100');
+  assert.strictEqual(seen[seen.length - 1], 'response');
+  const partial = seen.filter(s => s.startsWith('result')).map(s => s.split(' ')[2]);
+  assert.deepStrictEqual(partial, results.map(String));
+  assert.strictEqual(seen.filter(s => s.startsWith('progress')).length, 101);
+  await client.close();
+};
+
+// the names the instance and EventEmitter use can't be emitted from Go, so
+// an "exit" or "error" from the program neither ends the run early nor
+// throws for want of a listener
+const reserved = async () => {
+  const go = new Go(wasm, { capture: true });
+  const exits = [];
+  go.on('exit', code => exits.push(code));
+  await go.load();
+  for (const name of ['exit', 'error', 'newListener', 'removeListener']) {
+    assert.throws(() => go.global.GoEvents.emit(name, '1'), /is reserved for the host/);
+    assert.strictEqual(go.global.GoEvents.listening(name), false);
+  }
+  assert.deepStrictEqual(exits, []);
+  const { code } = await go.run('10');
+  assert.strictEqual(code, 0);
+  assert.deepStrictEqual(exits, [0]);
+};
+
+runTests([progress, partialResults, reserved]);
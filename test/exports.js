@@ -0,0 +1,51 @@
+// Calls the functions main.wasm exports to JS with -serve, which validate
+// their arguments like the command line and the RPC methods do.
+//
+//   npm run build:go && npm run test:exports
+const assert = require('assert');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const wasm = path.join(__dirname, '..', 'main.wasm');
+
+const serve = async () => {
+  const go = new Go(wasm, { capture: true });
+  go.run('-serve');
+  await go.waitReady();
+  return go;
+};
+
+const fib = async () => {
+  const go = await serve();
+  assert.strictEqual(go.exports.fib(10), 55);
+  assert.strictEqual(go.exports.fib(10, 'memo'), 55);
+  assert.strictEqual(go.exports.fib(10, undefined), 55);
+  assert.strictEqual(go.exports.fib(100, 'big'), '354224848179261915075');
+  for (const algo of [undefined, 'recursive', 'iterative', 'memo', 'matrix', 'big']) {
+    assert.throws(() => go.exports.fib(-5, algo), {
+      name: 'GoPanicError',
+      message: 'panic: n must not be negative, got -5',
+    });
+  }
+  assert.throws(() => go.exports.fib(1, 'nope'), { message: 'panic: unknown algorithm "nope"' });
+  assert.throws(() => go.exports.fib(), { message: 'panic: fib: want at least 1 arguments' });
+  // n is an int, which JS numbers beyond 2^53 can't hold exactly
+  assert.throws(() => go.exports.fib(2 ** 53, 'big'), { message: 'panic: interop: 9007199254740992 is not a safe integer' });
+  // the program survives invalid calls
+  assert.strictEqual(go.exports.fib(20, 'iterative'), 6765);
+};
+
+const fibString = async () => {
+  const go = await serve();
+  assert.strictEqual(go.exports.fibString(100, 'big'), '354224848179261915075');
+  assert.strictEqual(go.exports.fibString(0, 'matrix'), '0');
+  assert.throws(() => go.exports.fibString(-3, 'big'), {
+    name: 'GoPanicError',
+    message: 'panic: n must not be negative, got -3',
+  });
+  assert.throws(() => go.exports.fibString(1, 'nope'), { message: 'panic: unknown algorithm "nope"' });
+  assert.throws(() => go.exports.fibString(1), { message: 'panic: fibString: want 2 arguments' });
+};
+
+runTests([fib, fibString]);
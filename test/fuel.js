@@ -0,0 +1,102 @@
+// Instruments Main.go with cmd/wasmfuel and checks the fuel option of
+// Go.js: what runs use is reported, repeatably in deterministic mode, and a
+// run past its budget rejects with GoFuelError, which a call of an exported
+// Go function spending the rest throws.
+//
+//   npm run test:fuel
+const assert = require('assert');
+const { execFileSync } = require('child_process');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+
+const used = async (wasm, args, options = {}) => {
+  const go = new Go(wasm, Object.assign({ capture: true }, options));
+  const { code, stats } = await go.run(...args);
+  assert.strictEqual(code, 0);
+  assert.strictEqual(stats.fuel, go.fuelUsed);
+  return stats.fuel;
+};
+
+const reported = async (wasm) => {
+  // most of a short run is the runtime starting up, which varies a little
+  // with timing unless deterministic
+  const options = { deterministic: true };
+  const base = await used(wasm, ['-algo', 'recursive', '1'], options);
+  const small = await used(wasm, ['-algo', 'recursive', '15'], options) - base;
+  const large = await used(wasm, ['-algo', 'recursive', '25'], options) - base;
+  assert(small > 0, `fib(15) used ${small} more than fib(1)`);
+  assert(base > 0, `fib(1) used ${base}`);
+  // recursion does about 1.6 times the work for each step of n
+  assert(large > small * 50, `fib(25) used ${large} more than fib(1), fib(15) ${small}`);
+};
+
+const repeatable = async (wasm) => {
+  const args = ['-algo', 'recursive', '20'];
+  const first = await used(wasm, args, { deterministic: { seed: 1 } });
+  const second = await used(wasm, args, { deterministic: { seed: 1 } });
+  assert.strictEqual(second, first);
+};
+
+const budget = async (wasm) => {
+  const args = ['-algo', 'recursive', '25'];
+  const need = await used(wasm, args);
+  assert(await used(wasm, args, { fuel: need * 2 }) > 0);
+  // stopped halfway through and before the runtime finished starting
+  for (const fuel of [need / 2, 1000]) {
+    const go = new Go(wasm, { capture: true, fuel: Math.floor(fuel) });
+    await assert.rejects(go.run(...args), (err) => {
+      assert(err instanceof Go.GoFuelError);
+      assert.strictEqual(err.fuel, Math.floor(fuel));
+      assert(err.used >= err.fuel, `used ${err.used} of ${err.fuel}`);
+      return true;
+    });
+    assert(go.exited);
+    assert.strictEqual(go.stats.fuel, go.fuelUsed);
+
+    // the instance can be run again with another budget
+    go.fuel = need * 2;
+    go.reset();
+    assert.strictEqual((await go.run('-algo', 'iterative', '10')).code, 0);
+  }
+};
+
+// a Go function JS calls running out of fuel throws to its caller, and so
+// does calling it again
+const exported = async (wasm) => {
+  // startup uses the same fuel every time when deterministic
+  const probe = new Go(wasm, { capture: true, deterministic: true });
+  probe.run('-serve');
+  await probe.waitReady();
+  const go = new Go(wasm, { capture: true, deterministic: true, fuel: probe.fuelUsed + 1e7 });
+  const run = assert.rejects(go.run('-serve'), Go.GoFuelError);
+  await go.waitReady();
+  assert.strictEqual(go.exports.fib(10, 'recursive'), 55);
+  assert.throws(() => go.exports.fib(35, 'recursive'), Go.GoFuelError);
+  await run;
+  assert(go.exited);
+  assert.throws(() => go.exports.fib(1), Go.GoFuelError);
+};
+
+const uninstrumented = async (metered, dir) => {
+  const wasm = path.join(dir, 'main.wasm');
+  await assert.rejects(new Go(wasm, { fuel: 1000 }).run(), /instrumented by cmd\/wasmfuel/);
+  const go = new Go(wasm, { capture: true });
+  assert.strictEqual((await go.run('10')).code, 0);
+  assert.strictEqual(go.fuelUsed, undefined);
+  assert.strictEqual(go.stats.fuel, undefined);
+};
+
+runTests([reported, repeatable, budget, exported, uninstrumented], {
+  tmp: 'fuel',
+  setup: (dir) => {
+    execFileSync('go', ['build', '-o', path.join(dir, 'main.wasm'), '.'], {
+      cwd: root,
+      env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+    });
+    execFileSync('go', ['run', './cmd/wasmfuel', path.join(dir, 'main.wasm')], { cwd: root });
+    return path.join(dir, 'main-fuel.wasm');
+  },
+});
@@ -0,0 +1,92 @@
+// Checks what the globals, replaceGlobals and allow options let Go see:
+// dotted paths expose bound members only, whole objects stay as they are,
+// unknown names are left out, Go.defaultGlobals can't be changed, and
+// none of it keeps Go from reaching the host through Function.
+//
+//   npm run build:go && npm run test:globals
+const assert = require('assert');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const wasm = path.join(__dirname, '..', 'main.wasm');
+
+const service = {
+  name: 'service',
+  hello() {
+    return `hello from ${this.name}`;
+  },
+  bye() {
+    return 'bye';
+  },
+};
+
+const dotted = async () => {
+  const go = new Go(wasm, { globals: { service }, allow: ['service.hello', 'fs.write', 'fs.constants'] });
+  assert.deepStrictEqual(Object.keys(go.globals), ['service', 'fs']);
+  assert.deepStrictEqual(Object.keys(go.globals.service), ['hello']);
+  assert.strictEqual(go.globals.service.hello(), 'hello from service');
+  assert.deepStrictEqual(Object.keys(go.globals.fs), ['write', 'constants']);
+  assert.strictEqual(go.globals.fs.constants, Go.defaultGlobals.fs.constants);
+  assert.strictEqual(go.global.process, undefined);
+};
+
+const whole = async () => {
+  for (const allow of [['service'], ['service', 'service.hello'], ['service.hello', 'service']]) {
+    const go = new Go(wasm, { globals: { service }, allow });
+    assert.strictEqual(go.globals.service, service, allow.join(', '));
+  }
+};
+
+const unknown = async () => {
+  const go = new Go(wasm, { allow: ['console', 'nope', 'fs.nope', 'console.nope.deeper'] });
+  assert.deepStrictEqual(Object.keys(go.globals), ['console']);
+  assert.strictEqual(go.globals.console, console);
+};
+
+const replaced = async () => {
+  const go = new Go(wasm, { globals: { service }, replaceGlobals: true });
+  assert.deepStrictEqual(Object.keys(go.globals), ['service']);
+  const merged = new Go(wasm, { globals: { service, console: 'mine' } });
+  assert.strictEqual(merged.globals.service, service);
+  assert.strictEqual(merged.globals.console, 'mine');
+  assert.strictEqual(merged.globals.Uint8Array, Uint8Array);
+};
+
+const defaults = async () => {
+  assert(Object.isFrozen(Go.defaultGlobals));
+  assert.throws(() => {
+    'use strict';
+    Go.defaultGlobals.console = 'changed';
+  }, TypeError);
+  assert.strictEqual(new Go(wasm).globals.console, console);
+};
+
+// the program runs with just what it needs, replaced or allowed
+const runs = async () => {
+  const { fs, Object: O, Array: A } = Go.defaultGlobals;
+  const options = [
+    { allow: Go.runtimeGlobals },
+    { replaceGlobals: true, globals: { Object: O, Array: A, Uint8Array, fs: { write: fs.write, constants: fs.constants } } },
+  ];
+  for (const option of options) {
+    const go = new Go(wasm, Object.assign({ capture: true }, option));
+    const { code, stdout } = await go.run('-algo', 'iterative', '20');
+    assert.strictEqual(code, 0);
+    assert.strictEqual(stdout, 'fib(20) = 6765\n');
+  }
+};
+
+// allow is no sandbox: what Go reaches through the runtime's Object, as
+// js.Global().Get("Object").Get("constructor").Invoke(...) would, is the
+// host's Function and through it everything else
+const escape = async () => {
+  const go = new Go(wasm, { allow: Go.runtimeGlobals });
+  await go.load();
+  assert.strictEqual(go.global.process, undefined);
+  const fn = go.global.Object.constructor;
+  assert.strictEqual(fn, Function);
+  assert.strictEqual(fn('return process')(), process);
+};
+
+runTests([dotted, whole, unknown, replaced, defaults, runs, escape]);
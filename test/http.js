@@ -0,0 +1,128 @@
+// Serves Go http.Handlers through NodeHttp.js and checks what clients get:
+// main.wasm's /fib and the handlers of nodehttp/testdata/echo.
+//
+//   npm run build:go && npm run test:http
+const assert = require('assert');
+const { execFileSync } = require('child_process');
+const crypto = require('crypto');
+const http = require('http');
+const os = require('os');
+const path = require('path');
+const Go = require('../Go');
+const { createServer } = require('../NodeHttp');
+
+const root = path.join(__dirname, '..');
+
+const start = async (file, ...args) => {
+  const go = new Go(file);
+  go.run(...args);
+  await go.waitReady();
+  const server = createServer(go);
+  await new Promise(resolve => server.listen(0, '127.0.0.1', resolve));
+  return { go, server, port: server.address().port };
+};
+
+// request resolves to { status, headers, trailers, body } once the
+// response ended
+const request = (port, method, target, { headers = {}, body = [], trailers } = {}) => new Promise((resolve, reject) => {
+  const req = http.request({ port, method, path: target, headers, host: '127.0.0.1' }, (res) => {
+    const chunks = [];
+    res.on('data', chunk => chunks.push(chunk));
+    res.on('end', () => resolve({ status: res.statusCode, headers: res.headers, trailers: res.trailers, body: Buffer.concat(chunks) }));
+    res.on('error', reject);
+  });
+  req.on('error', reject);
+  body.forEach(chunk => req.write(chunk));
+  if (trailers) {
+    req.addTrailers(trailers);
+  }
+  req.end();
+});
+
+const fib = async () => {
+  const { server, port } = await start(path.join(root, 'main.wasm'), '-serve');
+  try {
+    let res = await request(port, 'GET', '/fib?n=30');
+    assert.strictEqual(res.status, 200);
+    assert.strictEqual(res.headers['content-type'], 'application/json');
+    assert.deepStrictEqual(JSON.parse(res.body), { n: 30, algo: 'recursive', fib: 832040 });
+
+    res = await request(port, 'GET', '/fib?n=100&algo=big');
+    assert.strictEqual(res.body.toString(), '{"n":100,"algo":"big","fib":354224848179261915075}\n');
+
+    res = await request(port, 'GET', '/fib?n=x');
+    assert.strictEqual(res.status, 400);
+    assert.strictEqual(res.body.toString(), 'invalid n "x"\n');
+
+    res = await request(port, 'GET', '/fib?n=1000000&algo=big');
+    assert.strictEqual(res.status, 400);
+    assert.strictEqual(res.body.toString(), 'n must be at most 100000 with algo big, got 1000000\n');
+
+    res = await request(port, 'GET', '/nope');
+    assert.strictEqual(res.status, 404);
+  } finally {
+    server.close();
+  }
+};
+
+const echo = async () => {
+  const dir = os.tmpdir();
+  const wasm = path.join(dir, 'nodehttp-echo.wasm');
+  execFileSync('go', ['build', '-o', wasm, './nodehttp/testdata/echo'], {
+    cwd: root,
+    env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+  });
+  const { go, server, port } = await start(wasm);
+  try {
+    // streamed request body with a trailer, echoed with response trailers
+    const chunks = Array.from({ length: 50 }, (_, i) => crypto.randomBytes(1000 + i));
+    const all = Buffer.concat(chunks);
+    let res = await request(port, 'PUT', '/echo?status=201', {
+      headers: { Trailer: 'X-Sent' },
+      body: chunks,
+      trailers: { 'X-Sent': 'yes' },
+    });
+    assert.strictEqual(res.status, 201);
+    assert.strictEqual(res.headers['x-method'], 'PUT');
+    assert(res.body.equals(all), 'echoed body differs');
+    assert.deepStrictEqual(res.trailers, {
+      'x-length': String(all.length),
+      'x-sum': crypto.createHash('sha256').update(all).digest('hex'),
+      'x-sent': 'yes',
+    });
+
+    // a body larger than Node buffers, written with backpressure
+    res = await request(port, 'GET', '/big?n=32');
+    assert.strictEqual(res.status, 200);
+    assert.strictEqual(res.body.length, 32 << 20);
+    assert.strictEqual(res.headers['content-type'], 'text/plain; charset=utf-8');
+
+    // a panic before writing answers 500, and the program keeps serving
+    res = await request(port, 'GET', '/panic');
+    assert.strictEqual(res.status, 500);
+
+    // the request's context is canceled when the client goes away
+    await new Promise((resolve, reject) => {
+      const req = http.get({ port, path: '/wait', host: '127.0.0.1' }, () => req.destroy());
+      req.on('error', () => { });
+      req.on('close', resolve);
+      setTimeout(() => reject(new Error('no response from /wait')), 5000);
+    });
+    for (let i = 0; i < 100 && !go.exports.canceled; i++) {
+      await new Promise(resolve => setTimeout(resolve, 10));
+    }
+    assert.strictEqual(go.exports.canceled, 'context canceled');
+  } finally {
+    server.close();
+  }
+};
+
+(async () => {
+  await fib();
+  await echo();
+  console.log('ok');
+  process.exit(0);
+})().catch((err) => {
+  console.error(err);
+  process.exit(1);
+});
@@ -0,0 +1,131 @@
+// Checks that Go.js refuses modules importing functions it doesn't
+// implement when it loads them, that cmd/gojscheck, which reads the same
+// hostFunctions list, agrees with it, and that the list has what the
+// gojs imports dispatch to.
+//
+//   npm run test:imports
+const assert = require('assert');
+const { execFileSync, spawnSync } = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const Go = require('../Go');
+const { readImports } = require('../WasmBinary');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+
+const name = s => [s.length, ...Buffer.from(s)];
+const section = (id, bytes) => [id, bytes.length, ...bytes];
+
+// a module built by a future Go, importing a function Go.js doesn't know
+// besides one it does
+const futureModule = () => Uint8Array.from([
+  0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+  ...section(1, [1, 0x60, 1, 0x7f, 0]),
+  ...section(2, [
+    2,
+    ...name('gojs'), ...name('runtime.wasmExit'), 0, 0,
+    ...name('gojs'), ...name('runtime.newInGo2'), 0, 0,
+  ]),
+  ...section(0, [...name('producers'), 1, ...name('language'), 1, ...name('Go'), ...name('go2.0')]),
+]);
+
+const implemented = async () => {
+  for (const fn of Go.hostFunctions) {
+    const method = fn.slice(fn.indexOf('.') + 1);
+    assert.strictEqual(typeof Go.prototype[method], 'function', `${fn} isn't implemented`);
+  }
+};
+
+// the methods of Go.js's runtime and syscall/js regions are what Go's
+// imports call through golangProxy, and hostFunctions must list exactly
+// those, or Go.js refuses modules it could run or accepts ones it can't
+const dispatched = async () => {
+  const src = fs.readFileSync(path.join(root, 'Go.js'), 'utf8');
+  const methods = [];
+  for (const pkg of ['runtime', 'syscall/js']) {
+    const region = src.split(`//#region ${pkg}\n`)[1].split('//#endregion')[0];
+    for (const [, method] of region.matchAll(/^ {2}([a-zA-Z]\w*)\(/gm)) {
+      methods.push(`${pkg}.${method}`);
+    }
+  }
+  assert.deepStrictEqual([...Go.hostFunctions].sort(), methods.sort());
+
+  const go = new Go(futureModule());
+  await assert.rejects(go.waitLoaded(), Go.GoImportError);
+  for (const fn of Go.hostFunctions) {
+    const calls = [];
+    go[fn.slice(fn.indexOf('.') + 1)] = addr => calls.push(addr);
+    go.golangProxy[fn](16);
+    assert.deepStrictEqual(calls, [16], `${fn} isn't dispatched`);
+  }
+};
+
+const releases = async () => {
+  for (const fn of Go.hostFunctions) {
+    assert.match(Go.importReleases[fn] || '', /^go1\.\d+$/, `${fn} has no release`);
+  }
+};
+
+const refused = async (dir) => {
+  const go = new Go(futureModule());
+  for (const promise of [go.waitLoaded(), go.run()]) {
+    await assert.rejects(promise, (err) => {
+      assert(err instanceof Go.GoImportError);
+      assert.deepStrictEqual(err.missing, ['gojs.runtime.newInGo2']);
+      assert.strictEqual(err.goVersion, 'go2.0');
+      assert.deepStrictEqual(err.releases, ['not in Go up to go1.27']);
+      assert.match(err.message, /built by go2\.0 .*gojs\.runtime\.newInGo2 \(not in Go up to go1\.27\)$/);
+      return true;
+    });
+  }
+
+  const file = path.join(dir, 'future.wasm');
+  fs.writeFileSync(file, futureModule());
+  const launched = spawnSync(process.execPath, [path.join(root, 'bin', 'go-in-js'), file], { encoding: 'utf8' });
+  assert.strictEqual(launched.status, 1);
+  assert.match(launched.stderr, /^go-in-js: .*future\.wasm: module built by go2\.0/);
+
+  const checked = spawnSync('go', ['run', './cmd/gojscheck', file], { cwd: root, encoding: 'utf8' });
+  assert.strictEqual(checked.status, 1);
+  assert.match(checked.stdout, /\(go2\.0\): 1 of 2 imports missing from Go\.js\n\tgojs\.runtime\.newInGo2 \(not in Go up to go1\.27\)\n/);
+};
+
+const accepted = async (dir) => {
+  const wasm = path.join(dir, 'main.wasm');
+  execFileSync('go', ['build', '-o', wasm, '.'], {
+    cwd: root,
+    env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+  });
+  const { code } = await new Go(wasm, { capture: true }).run('10');
+  assert.strictEqual(code, 0);
+  execFileSync('go', ['run', './cmd/gojscheck', wasm], { cwd: root });
+};
+
+// go-in-js tells wasip1 modules from js ones by their imports, which
+// readImports reads without compiling them
+const read = async (dir) => {
+  const kinds = Uint8Array.from([
+    0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+    ...section(1, [1, 0x60, 0, 0]),
+    ...section(2, [
+      4,
+      ...name('env'), ...name('table'), 1, 0x70, 0, 1,
+      ...name('env'), ...name('memory'), 2, 1, 0x80, 0x01, 0x80, 0x02,
+      ...name('env'), ...name('global'), 3, 0x7f, 0,
+      ...name('wasi_snapshot_preview1'), ...name('fd_write'), 0, 0,
+    ]),
+  ]);
+  const wasi = path.join(dir, 'wasi.wasm');
+  execFileSync('go', ['build', '-o', wasi, '.'], {
+    cwd: root,
+    env: Object.assign({}, process.env, { GOOS: 'wasip1', GOARCH: 'wasm' }),
+  });
+  for (const bytes of [kinds, futureModule(), fs.readFileSync(wasi)]) {
+    const want = WebAssembly.Module.imports(new WebAssembly.Module(bytes)).map(({ module, name }) => ({ module, name }));
+    assert.deepStrictEqual(readImports(bytes), want);
+  }
+  assert(readImports(fs.readFileSync(wasi)).some(imp => imp.module === 'wasi_snapshot_preview1'));
+};
+
+runTests([implemented, dispatched, releases, refused, accepted, read], { tmp: 'imports' });
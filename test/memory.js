@@ -0,0 +1,75 @@
+// Runs testdata/hog, which allocates the MiB it is asked for, under the
+// maxMemory option and checks how running out of memory is reported.
+//
+//   npm run test:memory
+const assert = require('assert');
+const { execFileSync } = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+const MiB = 1 << 20;
+
+const limited = async (wasm) => {
+  const go = new Go(wasm, { maxMemory: 64 * MiB, capture: true });
+  let code;
+  go.on('exit', (c) => {
+    code = c;
+  });
+  await assert.rejects(go.run('1000'), (err) => {
+    assert(err instanceof Go.GoMemoryError);
+    assert.strictEqual(err.maxMemory, 64 * MiB);
+    assert.strictEqual(err.requested, MiB);
+    assert(err.heapSize > 32 * MiB && err.heapSize <= 64 * MiB, `heapSize ${err.heapSize}`);
+    return true;
+  });
+  assert.strictEqual(code, 2);
+  assert(go.stats.peakMemory <= 64 * MiB);
+};
+
+// running out is told by the memory that's left, not by what the program
+// prints
+const mimicked = async (wasm) => {
+  const { code, stderr } = await new Go(wasm, { maxMemory: 64 * MiB, capture: true }).run('mimic');
+  assert.strictEqual(code, 2);
+  assert.match(stderr, /fatal error: out of memory/);
+};
+
+// a program failing for another reason near the limit keeps its exit code
+const failingNearLimit = async (wasm) => {
+  const probe = new Go(wasm, { capture: true });
+  await probe.run('40');
+  const limit = probe.stats.peakMemory + MiB;
+  const go = new Go(wasm, { maxMemory: limit, capture: true });
+  const { code } = await go.run('40', '1');
+  assert.strictEqual(code, 1);
+  assert(limit - go.stats.peakMemory < 4 * MiB, `peakMemory ${go.stats.peakMemory} of ${limit}`);
+};
+
+// within the limit the program runs as usual, from any source but a
+// compiled module
+const withinLimit = async (wasm) => {
+  const bytes = fs.readFileSync(wasm);
+  for (const source of [wasm, bytes, bytes.buffer.slice(bytes.byteOffset, bytes.byteOffset + bytes.length), fs.createReadStream(wasm)]) {
+    const { code, stdout } = await new Go(source, { maxMemory: 64 * MiB, capture: true }).run('10');
+    assert.strictEqual(code, 0);
+    assert.strictEqual(stdout.split('\n').length, 11);
+  }
+  const mod = new WebAssembly.Module(bytes);
+  await assert.rejects(new Go(mod, { maxMemory: 64 * MiB }).run(), /can't be changed/);
+  await assert.rejects(new Go(wasm, { maxMemory: MiB }).run(), /module starts with/);
+};
+
+runTests([limited, mimicked, failingNearLimit, withinLimit], {
+  tmp: 'memory',
+  setup: (dir) => {
+    const wasm = path.join(dir, 'hog.wasm');
+    execFileSync('go', ['build', '-o', wasm, './testdata/hog'], {
+      cwd: root,
+      env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+    });
+    return wasm;
+  },
+});
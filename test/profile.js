@@ -0,0 +1,117 @@
+// Profiles Main.go computing fib recursively with the cpuProfile option and
+// checks that the samples are of Go functions with their files and lines,
+// and that go tool pprof reads the profile Go.js and go-in-js write, and
+// that only one instance samples at a time.
+//
+//   npm run test:profile
+const assert = require('assert');
+const { execFileSync } = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+const args = ['-algo', 'recursive', '30'];
+
+const fibLine = fs.readFileSync(path.join(root, 'fib.go'), 'utf8').split('\n').indexOf('func fib(n int) int {') + 1;
+
+const sampled = async (wasm, dir) => {
+  const file = path.join(dir, 'cpu.pprof');
+  const go = new Go(wasm, { capture: true, cpuProfile: { file, interval: 100 } });
+  const { code } = await go.run(...args);
+  assert.strictEqual(code, 0);
+
+  const { funcs, samples } = go.profiler.profile;
+  const key = [...funcs.keys()].find(k => funcs.get(k).name === 'main.fib');
+  assert(key, 'main.fib not sampled');
+  assert.deepStrictEqual(funcs.get(key), {
+    name: 'main.fib',
+    systemName: 'main.fib',
+    file: path.join(root, 'fib.go'),
+    startLine: fibLine,
+    wasm: true,
+  });
+  // fib is called by main.main, through the algorithm's closure
+  const stack = samples.find(s => s.stack[0] === key).stack.map(k => funcs.get(k).name);
+  assert(stack.includes('main.main'), stack.join(' < '));
+  assert(!stack.some(name => name.startsWith('wasm_')), stack.join(' < '));
+
+  const top = execFileSync('go', ['tool', 'pprof', '-top', file], { encoding: 'utf8' });
+  assert.match(top, /Type: cpu/);
+  assert.match(top, / main\.fib$/m);
+};
+
+const cli = async (wasm, dir) => {
+  const file = path.join(dir, 'cli.pprof');
+  execFileSync(process.execPath, [path.join(root, 'bin', 'go-in-js'), '--cpuprofile', file, wasm, ...args]);
+  const top = execFileSync('go', ['tool', 'pprof', '-top', file], { encoding: 'utf8' });
+  assert.match(top, /^File: main\.wasm$/m);
+};
+
+const unsupported = async (wasm) => {
+  const go = new Go(wasm, { capture: true, cpuProfile: true });
+  go.platform = Object.assign({}, go.platform, { name: 'web', startProfiler: undefined });
+  await assert.rejects(go.run(...args), /CPU profiles need Node's inspector, not available on web/);
+};
+
+// only one instance samples at a time, and the profiler stops when run()
+// fails, so the next can
+const exclusive = async (wasm) => {
+  // -batch waits for the end of input
+  let end;
+  const input = new Promise((resolve) => {
+    end = resolve;
+  });
+  const first = new Go(wasm, { capture: true, cpuProfile: true, stdin: () => input });
+  const running = first.run('-batch');
+  await first.waitReady();
+  const second = new Go(wasm, { capture: true, cpuProfile: true });
+  await assert.rejects(second.run(...args), /another Profiler is sampling/);
+  end(null);
+  assert.strictEqual((await running).code, 0);
+
+  // Go reads fs as it starts, which throws out of run() without a trap
+  const failing = new Go(wasm, { capture: true, cpuProfile: true });
+  await failing.waitLoaded();
+  Object.defineProperty(failing.global, 'fs', {
+    get() {
+      throw new Error('no fs');
+    },
+  });
+  await assert.rejects(failing.run(...args), /no fs/);
+  assert(failing.profiler.profile);
+
+  const third = new Go(wasm, { capture: true, cpuProfile: true });
+  assert.strictEqual((await third.run('10')).code, 0);
+};
+
+// stopping a Profiler that isn't sampling fails and leaves the one that is
+// alone
+const notSampling = async (wasm) => {
+  let end;
+  const input = new Promise((resolve) => {
+    end = resolve;
+  });
+  const first = new Go(wasm, { capture: true, cpuProfile: true, stdin: () => input });
+  const running = first.run('-batch');
+  await first.waitReady();
+  const idle = new Go(wasm, { capture: true, cpuProfile: true });
+  await assert.rejects(idle.profiler.stop(), /not sampling/);
+  await assert.rejects(new Go(wasm, { capture: true, cpuProfile: true }).run(...args), /another Profiler is sampling/);
+  end(null);
+  assert.strictEqual((await running).code, 0);
+  await assert.rejects(first.profiler.stop(), /not sampling/);
+};
+
+runTests([sampled, cli, unsupported, exclusive, notSampling], {
+  tmp: 'profile',
+  setup: (dir) => {
+    const wasm = path.join(dir, 'main.wasm');
+    execFileSync('go', ['build', '-o', wasm, '.'], {
+      cwd: root,
+      env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+    });
+    return wasm;
+  },
+});
@@ -0,0 +1,78 @@
+// Runs the same JSON-RPC calls against Main.go -rpc under Go.js, under
+// WASI (through go-in-js) and as a native binary, and checks that every
+// transport answers them identically.
+//
+//   npm run test:rpc
+const assert = require('assert');
+const { execFileSync } = require('child_process');
+const fs = require('fs');
+const os = require('os');
+const path = require('path');
+const RpcClient = require('../RpcClient');
+
+const root = path.join(__dirname, '..');
+
+const build = (dir, out, env = {}) => {
+  const file = path.join(dir, out);
+  execFileSync('go', ['build', '-o', file, '.'], { cwd: root, env: Object.assign({}, process.env, env) });
+  return file;
+};
+
+// settle turns a call into { result } or { error } so failures compare too
+const settle = promise => promise.then(result => ({ result }), err => ({ error: { name: err.name, code: err.code, message: err.message } }));
+
+const calls = (client) => {
+  const pending = [
+    client.call('fib', [10]),
+    client.call('fib', { n: 100, algo: 'big' }),
+    client.call('fibRange', [0, 20, 'iterative']),
+    client.call('lucas', [30]),
+    client.call('lucas', { n: 200, algo: 'big' }),
+    client.call('fib', [93, 'matrix']),
+    client.call('fib', [-1]),
+    client.call('fibonacci', [1]),
+  ];
+  // many calls in flight at once, answered in any order
+  for (let n = 0; n < 200; n++) {
+    pending.push(client.call(n % 2 ? 'fib' : 'lucas', [n % 90, 'iterative']));
+  }
+  return Promise.all(pending.map(settle));
+};
+
+(async () => {
+  const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'go-rpc-'));
+  try {
+    const clients = {
+      'Go.js': RpcClient.go(build(dir, 'main.wasm', { GOOS: 'js', GOARCH: 'wasm' })),
+      'WASI': RpcClient.spawn(process.execPath, ['--no-warnings', path.join(root, 'bin', 'go-in-js'), build(dir, 'main-wasi.wasm', { GOOS: 'wasip1', GOARCH: 'wasm' }), '-rpc']),
+      'native': RpcClient.spawn(build(dir, 'fib')),
+    };
+
+    const results = {};
+    for (const [name, client] of Object.entries(clients)) {
+      results[name] = await calls(client);
+      client.notify('fib', [1]);
+      await client.close();
+      const done = await client.done;
+      assert.strictEqual(typeof done === 'number' ? done : done.code, 0, `${name} exit code`);
+      await assert.rejects(client.call('fib', [1]), /connection closed/);
+    }
+
+    const want = results.native;
+    assert.deepStrictEqual(want[0], { result: 55 });
+    assert.deepStrictEqual(want[1], { result: '354224848179261915075' });
+    assert.deepStrictEqual(want[3], { result: 1860498 });
+    assert.deepStrictEqual(want[5], { error: { name: 'RpcError', code: -32000, message: 'result overflows int64, use -algo big' } });
+    assert.strictEqual(want[6].error.code, -32602);
+    assert.strictEqual(want[7].error.code, -32601);
+    Object.entries(results).forEach(([name, got]) => {
+      assert.deepStrictEqual(got, want, `${name} differs from native`);
+    });
+    console.log(`ok: ${want.length} calls answered identically by ${Object.keys(results).join(', ')}`);
+  } finally {
+    fs.rmSync(dir, { recursive: true, force: true });
+  }
+})().catch((err) => {
+  console.error(err);
+  process.exitCode = 1;
+});
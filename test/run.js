@@ -0,0 +1,31 @@
+// The runner the test files share: it runs their tests one after another,
+// printing ok and the name of each that passes, and fails the process with
+// the first that throws.
+const fs = require('fs');
+const os = require('os');
+const path = require('path');
+
+// runTests runs tests in order. With tmp a temporary directory named
+// after it is made for them and removed once they're done; setup, given
+// the directory, prepares what they need, like a build into it. Each test
+// is called with what setup resolves to, the directory without a setup,
+// and the directory.
+const runTests = (tests, { tmp, setup } = {}) => (async () => {
+  const dir = tmp && fs.mkdtempSync(path.join(os.tmpdir(), `go-${tmp}-`));
+  try {
+    const value = setup ? await setup(dir) : dir;
+    for (const test of tests) {
+      await test(value, dir);
+      console.log(`ok ${test.name}`);
+    }
+  } finally {
+    if (dir) {
+      fs.rmSync(dir, { recursive: true, force: true });
+    }
+  }
+})().catch((err) => {
+  console.error(err);
+  process.exitCode = 1;
+});
+
+module.exports = runTests;
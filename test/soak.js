@@ -0,0 +1,33 @@
+// Calls the exported fib a million times and checks the JS value table
+// stays bounded, i.e. values Go let go of through finalizeRef are reused.
+//
+//   npm run build:go && npm run test:soak
+const assert = require('assert');
+const Go = require('../Go');
+
+const calls = Number(process.argv[2]) || 1000000;
+
+(async () => {
+  const go = new Go(`${__dirname}/../main.wasm`);
+  go.run('-serve');
+  await go.waitReady();
+
+  // Go only finalizes values when its GC runs, so the table first grows to
+  // whatever Go's heap goal allows and must then stay there.
+  let peak = 0;
+  let warmPeak = 0;
+  for (let i = 0; i < calls; i++) {
+    assert.strictEqual(go.exports.fib(10), 55);
+    peak = Math.max(peak, go._values.length);
+    if (i === Math.floor(calls / 10)) {
+      warmPeak = peak;
+    }
+  }
+
+  console.log(`${calls} calls, ${go.stats.values} values allocated, ${go.liveValues} live, table size ${go._values.length} (peak ${peak})`);
+  assert.ok(peak < go.stats.values / 4, `value table grew to ${peak} entries for ${go.stats.values} values`);
+  assert.ok(peak <= warmPeak * 1.5, `value table kept growing from ${warmPeak} to ${peak} entries`);
+})().catch((err) => {
+  console.error(err);
+  process.exitCode = 1;
+});
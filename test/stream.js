@@ -0,0 +1,109 @@
+// Pipes lines through Main.go -batch with go.createStream and checks the
+// results, that backpressure holds both ways and how the stream ends.
+//
+//   npm run build:go && npm run test:stream
+const assert = require('assert');
+const path = require('path');
+const { PassThrough, Readable, Writable } = require('stream');
+const { pipeline } = require('stream/promises');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const wasm = path.join(__dirname, '..', 'main.wasm');
+
+// collect reads a stream to the end as a string
+const collect = async (stream) => {
+  const chunks = [];
+  for await (const chunk of stream) {
+    chunks.push(chunk);
+  }
+  return Buffer.concat(chunks).toString();
+};
+
+const fibs = (count) => {
+  const want = [];
+  let [a, b] = [0n, 1n];
+  for (let n = 0; n < count; n++) {
+    want.push(`fib(${n}) = ${a}\n`);
+    [a, b] = [b, a + b];
+  }
+  return want;
+};
+
+// many lines written in small chunks through a pipeline
+const bulk = async () => {
+  const count = 5000;
+  const lines = Array.from({ length: count }, (_, n) => `${n} big\n`);
+  const stream = new Go(wasm).createStream('-batch');
+  const out = collect(stream);
+  await pipeline(Readable.from(lines), stream);
+  assert.strictEqual(await out, fibs(count).join(''));
+};
+
+// a slow reader holds Go back instead of it buffering all of its output
+const backpressure = async () => {
+  const stream = new Go(wasm).createStream('-batch');
+  const input = new PassThrough();
+  input.pipe(stream);
+
+  // about 2MB of results asked for at once
+  const count = 5000;
+  input.write(Array.from({ length: count }, (_, n) => `${n} big\n`).join(''));
+  const results = [];
+  const slow = new Writable({
+    highWaterMark: 1,
+    write(chunk, encoding, callback) {
+      results.push(chunk);
+      setTimeout(callback, 1);
+    },
+  });
+  stream.pipe(slow);
+  await new Promise(resolve => setTimeout(resolve, 100));
+  // Go waits for the reader, its output isn't all in memory
+  assert(stream.readableLength <= stream.readableHighWaterMark, `readable side holds ${stream.readableLength} bytes`);
+  assert(Buffer.concat(results).length < 1 << 20, 'the slow reader got most of the output already');
+  input.end();
+  await new Promise(resolve => slow.on('finish', resolve));
+  assert.strictEqual(Buffer.concat(results).toString(), fibs(count).join(''));
+};
+
+// an exit code other than 0 destroys the stream with an error
+const failure = async () => {
+  const stream = new Go(wasm, { capture: true }).createStream('-batch');
+  stream.end('10 guess\n');
+  await assert.rejects(collect(stream), err => err.exitCode === 2 && /exited with code 2/.test(err.message));
+};
+
+// destroying the stream ends Go's stdin, so the program finishes
+const destroy = async () => {
+  const go = new Go(wasm);
+  const stream = go.createStream('-batch');
+  stream.write('5\n');
+  const first = await new Promise(resolve => stream.once('data', resolve));
+  assert.strictEqual(first.toString(), 'fib(5) = 5\n');
+  stream.destroy();
+  const code = await new Promise(resolve => go.exit = resolve);
+  assert.strictEqual(code, 0);
+};
+
+// the stream is the stdio of its run only, the instance runs again with
+// its own after it ended
+const again = async () => {
+  const go = new Go(wasm, { capture: true });
+  const stream = go.createStream('-batch');
+  stream.end('5\n');
+  assert.strictEqual(await collect(stream), 'fib(5) = 5\n');
+  go.reset();
+  const { code, stdout } = await go.run('7');
+  assert.strictEqual(code, 0);
+  assert.strictEqual(stdout, 'fib(7) = 13\n');
+
+  go.reset();
+  const failed = go.createStream('-batch');
+  failed.end('10 guess\n');
+  await assert.rejects(collect(failed), { exitCode: 2 });
+  go.reset();
+  assert.strictEqual((await go.run('7')).stdout, 'fib(7) = 13\n');
+};
+
+runTests([bulk, backpressure, failure, destroy, again]);
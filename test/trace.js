@@ -0,0 +1,110 @@
+// Traces the calls main.wasm makes into Go.js: their counts and the Chrome
+// trace of them, kept whole with a file and bounded without, and calls
+// that throw.
+//
+//   npm run build:go && npm run test:trace
+const assert = require('assert');
+const fs = require('fs');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const wasm = path.join(__dirname, '..', 'main.wasm');
+
+const calls = stats => Object.values(stats).reduce((sum, { calls }) => sum + calls, 0);
+
+const stats = async () => {
+  const go = new Go(wasm, { trace: true, capture: true });
+  const { stdout } = await go.run('10');
+  const stats = go.tracer.stats();
+  assert.deepStrictEqual(Object.keys(stats), Object.keys(stats).sort());
+  assert.strictEqual(stats['runtime.wasmExit'].calls, 1);
+  assert(stats['syscall/js.valueCall'].calls >= 1);
+  for (const [name, { calls, time }] of Object.entries(stats)) {
+    assert(Go.hostFunctions.includes(name), name);
+    assert(calls > 0 && time >= 0, name);
+  }
+
+  // the tracer starts over with every run
+  go.reset();
+  await go.run('10');
+  assert.deepStrictEqual(Object.keys(go.tracer.stats()), Object.keys(stats));
+  assert.strictEqual(go.tracer.stats()['runtime.wasmExit'].calls, 1);
+  assert.strictEqual(stdout, 'fib(10) = 55\n');
+};
+
+const chromeTrace = async () => {
+  const go = new Go(wasm, { trace: true, capture: true });
+  await go.run('10');
+  const { traceEvents, displayTimeUnit } = go.tracer.chromeTrace();
+  assert.strictEqual(displayTimeUnit, 'ms');
+  assert.strictEqual(traceEvents.length, calls(go.tracer.stats()));
+  // calls are recorded as they return, so one re-entering Go comes after
+  // those Go made meanwhile
+  for (const event of traceEvents) {
+    assert.strictEqual(event.ph, 'X');
+    assert.strictEqual(event.cat, event.name.split('.')[0]);
+    assert(event.ts >= 0 && event.dur >= 0, event.name);
+  }
+  const exit = traceEvents[traceEvents.length - 1];
+  assert.strictEqual(exit.name, 'runtime.wasmExit');
+  assert.deepStrictEqual(exit.args, { code: 0 });
+  // fmt writes to stdout through fs.write
+  const write = traceEvents.find(e => e.name === 'syscall/js.valueCall' && e.args.m === '"write"');
+  assert(write, 'no fs.write call');
+  assert.match(write.args.args, /^\[1, Uint8Array\(13\), 0, 13, /);
+};
+
+// without a file only the latest events are kept, however long it runs
+const bounded = async () => {
+  const go = new Go(wasm, { trace: true, capture: true });
+  go.tracer.maxEvents = 5;
+  await go.run('100');
+  assert(calls(go.tracer.stats()) > 10);
+  assert(go.tracer.events.length < 10);
+  const { traceEvents } = go.tracer.chromeTrace();
+  assert.strictEqual(traceEvents.length, 5);
+  assert.strictEqual(traceEvents[4].name, 'runtime.wasmExit');
+};
+
+const file = async (dir) => {
+  const file = path.join(dir, 'trace.json');
+  const go = new Go(wasm, { trace: file, capture: true });
+  go.tracer.maxEvents = 5;
+  await go.run('100');
+  const { traceEvents } = JSON.parse(fs.readFileSync(file, 'utf8'));
+  assert.strictEqual(traceEvents.length, calls(go.tracer.stats()));
+  assert.strictEqual(traceEvents[traceEvents.length - 1].name, 'runtime.wasmExit');
+};
+
+// a call that throws, like one re-entering Go that traps, records the
+// error instead of reading results off a stack it never wrote them to
+const threw = async () => {
+  const reads = [];
+  const go = {
+    now: 0,
+    instance: {},
+    sp: 100,
+    getInt64: (addr) => {
+      reads.push(addr);
+      return 5;
+    },
+    getInt32: (addr) => {
+      reads.push(addr);
+      return 7;
+    },
+  };
+  const tracer = new Go.Tracer(go);
+  assert.throws(() => tracer.call('runtime.scheduleTimeoutEvent', 0, () => {
+    throw new RangeError('boom');
+  }), /boom/);
+  assert.deepStrictEqual(reads, [8]);
+  assert.deepStrictEqual(tracer.events[0].args, { delay: 5, error: 'RangeError: boom' });
+
+  assert.strictEqual(tracer.call('runtime.scheduleTimeoutEvent', 0, () => 'ok'), 'ok');
+  assert.deepStrictEqual(reads, [8, 8, 116]);
+  assert.deepStrictEqual(tracer.events[1].args, { delay: 5, result: { id: 7 } });
+  assert.deepStrictEqual(tracer.stats()['runtime.scheduleTimeoutEvent'].calls, 2);
+};
+
+runTests([stats, chromeTrace, bounded, file, threw], { tmp: 'trace' });
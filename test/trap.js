@@ -0,0 +1,97 @@
+// Runs testdata/trap, which reads memory out of bounds, and checks that
+// run() rejects with the trap and a Go stack trace naming its functions
+// and lines, whether it happens before or after Go first returned to JS,
+// and that a call of an exported Go function throws it.
+//
+//   npm run test:trap
+const assert = require('assert');
+const { execFileSync, spawnSync } = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const Go = require('../Go');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+const source = fs.readFileSync(path.join(root, 'testdata', 'trap', 'main.go'), 'utf8').split('\n');
+
+// line returns the line of testdata/trap/main.go ending in the comment
+const line = comment => source.findIndex(l => l.endsWith(`// ${comment}`)) + 1;
+
+const goStack = () => [
+  `main.poke(...)\n\t${root}/testdata/trap/main.go:${line('trap')} `,
+  ...[0, 1, 1, 1].map(depth => `main.deep(...)\n\t${root}/testdata/trap/main.go:${line(depth ? 'deep' : 'bottom')} `),
+  `main.main(...)\n\t${root}/testdata/trap/main.go:${line('main')} `,
+  'runtime.main(...)',
+];
+
+const trapped = async (wasm) => {
+  for (const args of [[], ['late']]) {
+    const go = new Go(wasm, { capture: true });
+    await assert.rejects(go.run(...args), (err) => {
+      assert(err instanceof WebAssembly.RuntimeError);
+      assert.match(err.message, /out of bounds/);
+      let at = 0;
+      for (const frame of goStack()) {
+        at = err.goStack.indexOf(frame, at);
+        assert(at !== -1, `${frame} not in\n${err.goStack}`);
+      }
+      assert(err.stack.endsWith(err.goStack));
+      return true;
+    });
+    assert(go.exited);
+  }
+};
+
+// a trap in a Go function JS calls throws to its caller, with the Go stack
+const exported = async (wasm) => {
+  const go = new Go(wasm, { capture: true });
+  const run = go.run('export');
+  await go.waitReady();
+  let trap;
+  assert.throws(() => go.exports.deep(2), (err) => {
+    assert(err instanceof WebAssembly.RuntimeError);
+    assert.match(err.goStack, /^main\.poke\(\.\.\.\)\n/);
+    assert(err.goStack.includes(`main.main.func1(...)\n\t${root}/testdata/trap/main.go:${line('export')} `), err.goStack);
+    trap = err;
+    return true;
+  });
+  await assert.rejects(run, err => err === trap);
+  assert.throws(() => go.exports.deep(2), err => err === trap);
+};
+
+// a compiled module leaves Symbols only the name section
+const named = async (wasm) => {
+  const module = new WebAssembly.Module(fs.readFileSync(wasm));
+  await assert.rejects(new Go(module, { capture: true }).run(), (err) => {
+    assert.match(err.goStack, /^main\.poke\(\.\.\.\)\n\twasm-function\[\d+\]:0x[0-9a-f]+\nmain\.deep/);
+    return true;
+  });
+};
+
+const symbols = async (wasm) => {
+  const bytes = fs.readFileSync(wasm);
+  const syms = new Go.Symbols(new WebAssembly.Module(bytes), bytes);
+  const index = [...syms.names].find(([, name]) => name === 'main.deep')[0];
+  assert.strictEqual(syms.name(index), 'main.deep');
+  assert.strictEqual(syms.frame(index).name, 'main.deep');
+  assert.deepStrictEqual(syms.frames('at x (wasm://wasm/1:wasm-function[1]:0x0)').map(f => f.index), [1]);
+  assert.strictEqual(syms.traceback('no wasm frames'), '');
+};
+
+const cli = async (wasm) => {
+  const run = spawnSync(process.execPath, [path.join(root, 'bin', 'go-in-js'), wasm], { encoding: 'utf8' });
+  assert.strictEqual(run.status, 2);
+  assert.match(run.stderr, /^go-in-js: wasm trap: memory access out of bounds\n\nmain\.poke\(\.\.\.\)\n/);
+};
+
+runTests([trapped, exported, named, symbols, cli], {
+  tmp: 'trap',
+  setup: (dir) => {
+    const wasm = path.join(dir, 'trap.wasm');
+    execFileSync('go', ['build', '-o', wasm, './testdata/trap'], {
+      cwd: root,
+      env: Object.assign({}, process.env, { GOOS: 'js', GOARCH: 'wasm' }),
+    });
+    return wasm;
+  },
+});
@@ -0,0 +1,91 @@
+// Runs wasip1 builds under GoWasi: captured stdio, files through
+// preopens, and stdin from a pipe that has nothing to read yet, which
+// needs the fdflags workaround. test/rpc.js checks it answers like Go.js.
+//
+//   npm run test:wasi
+const assert = require('assert');
+const { execFileSync, spawn } = require('child_process');
+const fs = require('fs');
+const path = require('path');
+const GoWasi = require('../GoWasi');
+const runTests = require('./run');
+
+const root = path.join(__dirname, '..');
+const wasip1 = { GOOS: 'wasip1', GOARCH: 'wasm' };
+
+const build = (dir, out, pkg) => {
+  const file = path.join(dir, out);
+  execFileSync('go', ['build', '-o', file, pkg], { cwd: root, env: Object.assign({}, process.env, wasip1) });
+  return file;
+};
+
+const capture = async ({ main }) => {
+  const go = new GoWasi(main, { capture: true });
+  const exits = [];
+  go.exit = code => exits.push(code);
+  const { code, stdout, stderr, stats } = await go.run('10');
+  assert.strictEqual(code, 0);
+  assert.strictEqual(stdout, 'fib(10) = 55\n');
+  assert.strictEqual(stderr, '');
+  assert(stats.wasmTime > 0 && stats.peakMemory > 0);
+  assert.deepStrictEqual(exits, [0]);
+  assert(go.exited && !go.running);
+
+  const bad = await new GoWasi(main, { capture: true, argv0: 'fib' }).run('-algo', 'nope', '10');
+  assert.strictEqual(bad.code, 2);
+  assert.strictEqual(bad.stdout, '');
+  assert.match(bad.stderr, /nope/);
+};
+
+const preopens = async ({ cat, dir }) => {
+  const data = path.join(dir, 'data');
+  fs.mkdirSync(data);
+  fs.writeFileSync(path.join(data, 'in.txt'), 'from the host\n');
+  const options = { capture: true, preopens: { '/data': data } };
+
+  const read = await new GoWasi(cat, options).run('/data/in.txt');
+  assert.strictEqual(read.code, 0);
+  assert.strictEqual(read.stdout, 'from the host\n');
+
+  const write = await new GoWasi(cat, options).run('-o', '/data/out.txt', '/data/in.txt');
+  assert.strictEqual(write.code, 0);
+  assert.strictEqual(fs.readFileSync(path.join(data, 'out.txt'), 'utf8'), 'from the host\n');
+
+  // only what's preopened is there
+  const outside = await new GoWasi(cat, options).run(path.join(dir, 'cat.wasm'));
+  assert.strictEqual(outside.code, 1);
+  assert.match(outside.stderr, /^cat: /);
+};
+
+// Go makes stdin non-blocking and polls it when a read would block, which
+// only works if fd_fdstat_get reports the flag back; go-in-js runs the
+// program with the pipe it is given as stdin
+const nonblocking = async ({ cat }) => {
+  const child = spawn(process.execPath, ['--no-warnings', path.join(root, 'bin', 'go-in-js'), cat]);
+  let stdout = '';
+  let stderr = '';
+  child.stdout.on('data', (chunk) => {
+    stdout += chunk;
+  });
+  child.stderr.on('data', (chunk) => {
+    stderr += chunk;
+  });
+  const exited = new Promise(resolve => child.on('close', resolve));
+
+  for (const line of ['one\n', 'two\n', 'three\n']) {
+    await new Promise(resolve => setTimeout(resolve, 100));
+    child.stdin.write(line);
+  }
+  child.stdin.end();
+  assert.strictEqual(await exited, 0, stderr);
+  assert.strictEqual(stdout, 'one\ntwo\nthree\n');
+};
+
+runTests([capture, preopens, nonblocking], {
+  tmp: 'wasi',
+  setup: dir => ({
+    dir,
+    main: build(dir, 'main.wasm', '.'),
+    cat: build(dir, 'cat.wasm', './testdata/cat'),
+  }),
+});
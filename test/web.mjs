@@ -0,0 +1,126 @@
+// Loads esm/web.mjs the way browsers do, with Go.js and its companions as
+// plain scripts rather than CommonJS, and runs main.wasm on the web
+// platform, whose output goes to the console line by line. Also checks
+// platform/web.mjs's stubs and that instances keep their own platform.
+//
+//   npm run build:go && npm run test:web
+import assert from 'assert';
+import { EventEmitter } from 'events';
+import fs from 'fs';
+import { createRequire, register } from 'module';
+
+// outside CommonJS, as in a browser, the scripts put their classes on
+// globalThis for esm/web.mjs to pick up
+const scripts = /\/(Go|Tracer|Symbols|Profiler|Emitter|VirtualClock|WasmBinary|Imports)\.js$/;
+register(`data:text/javascript,${encodeURIComponent(`
+  export async function load(url, context, next) {
+    return next(url, ${scripts}.test(url) ? { ...context, format: 'module' } : context);
+  }
+`)}`, import.meta.url);
+
+const { default: Go, GoFuelError } = await import('../esm/web.mjs');
+const { default: web } = await import('../platform/web.mjs');
+const require = createRequire(import.meta.url);
+const NodeGo = require('../Go.js');
+const runTests = require('./run.js');
+
+const wasm = fs.readFileSync(new URL('../main.wasm', import.meta.url));
+
+// the console output of fn, as console.log and console.error get it
+const consoleOf = async (fn) => {
+  const { log, error } = console;
+  const out = [];
+  console.log = line => out.push(['log', line]);
+  console.error = line => out.push(['error', line]);
+  try {
+    return [await fn(), out];
+  } finally {
+    Object.assign(console, { log, error });
+  }
+};
+
+const entry = async () => {
+  assert.notStrictEqual(Go, NodeGo);
+  assert.strictEqual(Go.platform, web);
+  assert.strictEqual(web.name, 'web');
+  assert.strictEqual(Go.GoFuelError, GoFuelError);
+  assert.strictEqual(typeof Go.Tracer, 'function');
+  assert.strictEqual(typeof Go.Symbols, 'function');
+  assert.strictEqual(typeof Go.Profiler, 'function');
+  assert.strictEqual(typeof Go.VirtualClock, 'function');
+  assert.deepStrictEqual(Go.hostFunctions, NodeGo.hostFunctions);
+  // without Node's events the instances are Emitter.js's emitters
+  assert(!(new Go(wasm) instanceof EventEmitter));
+  for (const name of ['Go', 'GoTracer', 'GoSymbols', 'GoProfiler', 'GoEmitter', 'GoVirtualClock', 'GoWasmBinary', 'GoImports']) {
+    assert.strictEqual(globalThis[name], undefined, name);
+  }
+  assert.strictEqual(Go.defaultGlobals.fs, web.globals.fs);
+};
+
+const runs = async () => {
+  const [{ code }, out] = await consoleOf(() => new Go(wasm).run('-algo', 'iterative', '20'));
+  assert.strictEqual(code, 0);
+  assert.deepStrictEqual(out, [['log', 'fib(20) = 6765']]);
+  const [, failed] = await consoleOf(() => new Go(wasm).run('-algo', 'nope'));
+  assert.deepStrictEqual(failed, [['error', 'unknown algorithm "nope"']]);
+};
+
+const stubs = async () => {
+  const [, out] = await consoleOf(() => {
+    const encode = s => new TextEncoder().encode(s);
+    assert.strictEqual(web.writeSync(1, encode('par')), 3);
+    assert.strictEqual(web.writeSync(1, encode('tial\nrest')), 9);
+    assert.strictEqual(web.writeSync(2, encode('err\n')), 4);
+    web.writeSync(1, encode('\n'));
+  });
+  assert.deepStrictEqual(out, [['log', 'partial'], ['error', 'err'], ['log', 'rest']]);
+  assert.throws(() => web.writeSync(3, new Uint8Array(1)), { code: 'ENOSYS' });
+
+  const { fs: webFs, process } = web.globals;
+  const result = await new Promise(resolve => webFs.open('/etc/passwd', 0, 0, (...args) => resolve(args)));
+  assert.strictEqual(result[0].code, 'ENOSYS');
+  await new Promise((resolve) => {
+    webFs.write(1, new Uint8Array(2), 1, 1, null, (err) => {
+      assert.strictEqual(err.code, 'ENOSYS');
+      resolve();
+    });
+  });
+  assert.throws(() => process.cwd(), { code: 'ENOSYS' });
+
+  // more than getRandomValues fills at once
+  const buf = new Uint8Array(200000);
+  web.randomFill(buf);
+  assert(buf.subarray(150000).some(b => b !== 0));
+  assert.throws(() => web.writeFile('trace.json', '{}'), /browsers have no file system/);
+  assert.strictEqual(web.startProfiler, undefined);
+  assert.strictEqual(web.Duplex, undefined);
+};
+
+// instances run on their own platform, whatever the default is
+const perInstance = async () => {
+  const onWeb = new NodeGo(wasm, { platform: web });
+  assert.strictEqual(onWeb.platform, web);
+  assert.strictEqual(onWeb.globals.fs, web.globals.fs);
+  const onNode = new NodeGo(wasm);
+  assert.strictEqual(onNode.platform.name, 'node');
+  assert.strictEqual(onNode.globals.fs, fs);
+
+  const [{ code }, out] = await consoleOf(() => onWeb.run('-algo', 'iterative', '10'));
+  assert.strictEqual(code, 0);
+  assert.deepStrictEqual(out, [['log', 'fib(10) = 55']]);
+  assert.throws(() => onWeb.createStream(), /the web platform has none/);
+
+  const node = NodeGo.platform;
+  NodeGo.usePlatform(web);
+  try {
+    assert.strictEqual(new NodeGo(wasm).platform, web);
+    assert.strictEqual(NodeGo.defaultGlobals.fs, web.globals.fs);
+    assert.strictEqual(onNode.platform, node);
+    assert.strictEqual(onNode.globals.fs, fs);
+  } finally {
+    NodeGo.usePlatform(node);
+  }
+  assert.strictEqual(NodeGo.defaultGlobals.fs, fs);
+};
+
+runTests([entry, runs, stubs, perInstance]);
@@ -0,0 +1,48 @@
+// Command cat copies the files it is given to stdout, or stdin without
+// any, to check how a host runs programs doing file and pipe I/O. With -o
+// it writes to that file instead.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+var out = flag.String("o", "", "write to this file instead of stdout")
+
+func main() {
+	flag.Parse()
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if flag.NArg() == 0 {
+		if _, err := io.Copy(w, os.Stdin); err != nil {
+			fail(err)
+		}
+		return
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fail(err)
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			fail(err)
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "cat:", err)
+	os.Exit(1)
+}
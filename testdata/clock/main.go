@@ -0,0 +1,27 @@
+// Command clock prints what a program sees of time and randomness: the
+// time, the time after sleeping each duration it is given, and random
+// bytes, to check deterministic runs.
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	fmt.Println(time.Now().UTC().Format(time.RFC3339))
+	for _, arg := range os.Args[1:] {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "usage: clock [duration...]")
+			os.Exit(2)
+		}
+		time.Sleep(d)
+		fmt.Println(time.Now().UTC().Format(time.RFC3339))
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	fmt.Printf("%x\n", b)
+}
@@ -0,0 +1,38 @@
+// Command hog allocates and keeps the given number of MiB, printing each
+// MiB it got, to run programs out of memory, and exits with the code given
+// after the MiB, 0 by default. hog mimic fails with what the runtime
+// prints when it runs out, without running out.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var kept [][]byte
+
+func main() {
+	if os.Args[1] == "mimic" {
+		fmt.Fprintln(os.Stderr, "runtime: out of memory: cannot allocate 1048576-byte block (0 in use)")
+		fmt.Fprintln(os.Stderr, "fatal error: out of memory")
+		os.Exit(2)
+	}
+	mib, err := strconv.Atoi(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "usage: hog MiB [code]")
+		os.Exit(2)
+	}
+	code := 0
+	if len(os.Args) > 2 {
+		if code, err = strconv.Atoi(os.Args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, "usage: hog MiB [code]")
+			os.Exit(2)
+		}
+	}
+	for i := 0; i < mib; i++ {
+		kept = append(kept, make([]byte, 1<<20))
+		fmt.Println(i + 1)
+	}
+	os.Exit(code)
+}
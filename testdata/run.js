@@ -0,0 +1,20 @@
+// Runs a js/wasm binary through Go.js with the given arguments, forwarding
+// its output and exit code. Used by main_test.go. --deterministic runs it
+// on a virtual clock with seeded random data.
+//
+//   node testdata/run.js [--deterministic] main.wasm [args...]
+const Go = require('../Go');
+
+const argv = process.argv.slice(2);
+const deterministic = argv[0] === '--deterministic';
+if (deterministic) {
+  argv.shift();
+}
+const [file, ...args] = argv;
+
+new Go(file, { deterministic }).run(...args).then(({ code }) => {
+  process.exitCode = code;
+}).catch((err) => {
+  console.error(err);
+  process.exitCode = 1;
+});
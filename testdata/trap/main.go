@@ -0,0 +1,41 @@
+// Command trap reads memory past the end of its linear memory, which traps
+// the wasm module, a few calls deep. With the argument late it first
+// prints, so the trap happens after Go returned to JS and was resumed. With
+// export it exports deep to JS instead and waits for it to be called.
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall/js"
+	"unsafe"
+
+	"go-to-js/interop"
+)
+
+//go:noinline
+func poke(n int) int {
+	p := (*int)(unsafe.Pointer(uintptr(0xf0000000 + n)))
+	return *p // trap
+}
+
+//go:noinline
+func deep(n int) int {
+	if n == 0 {
+		return poke(n) // bottom
+	}
+	return deep(n-1) + 1 // deep
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		interop.Export("deep", func(this js.Value, args []js.Value) any {
+			return deep(args[0].Int()) // export
+		})
+		select {}
+	}
+	if len(os.Args) > 1 && os.Args[1] == "late" {
+		fmt.Println("late")
+	}
+	fmt.Println(deep(3)) // main
+}
@@ -0,0 +1,182 @@
+package wasm
+
+import "fmt"
+
+// Op is an opcode. Prefixed opcodes are the prefix byte followed by the
+// LEB128 sub-opcode's low byte, e.g. 0xfc0a for memory.copy.
+type Op uint16
+
+// Opcodes the tools in this module look for or emit.
+const (
+	OpUnreachable  Op = 0x00
+	OpBlock        Op = 0x02
+	OpLoop         Op = 0x03
+	OpIf           Op = 0x04
+	OpElse         Op = 0x05
+	OpEnd          Op = 0x0b
+	OpBr           Op = 0x0c
+	OpBrIf         Op = 0x0d
+	OpBrTable      Op = 0x0e
+	OpReturn       Op = 0x0f
+	OpCall         Op = 0x10
+	OpCallIndirect Op = 0x11
+	OpGlobalGet    Op = 0x23
+	OpGlobalSet    Op = 0x24
+	OpI32Const     Op = 0x41
+	OpI64Const     Op = 0x42
+	OpI64LtS       Op = 0x53
+	OpI64Sub       Op = 0x7d
+)
+
+// BlockVoid is the block type of blocks without results.
+const BlockVoid = 0x40
+
+// Instruction is an instruction of a function body or constant expression.
+type Instruction struct {
+	Op Op
+	// Offset and End delimit the instruction in the data it was read from.
+	Offset, End int
+	// Index is the immediate of instructions taking a single index, like
+	// call, br and global.get.
+	Index uint32
+}
+
+// Instruction reads an instruction.
+func (r *Reader) Instruction() Instruction {
+	in := Instruction{Offset: r.off}
+	c := r.Byte()
+	in.Op = Op(c)
+	switch {
+	case c == 0x02 || c == 0x03 || c == 0x04: // block, loop, if
+		r.blockType()
+	case c == 0x0c || c == 0x0d || c == 0x10 || c == 0x12 || c == 0xd2 || c >= 0x20 && c <= 0x26:
+		// br, br_if, call, return_call, ref.func, local, global and table
+		// get and set
+		in.Index = r.U32()
+	case c == 0x0e: // br_table
+		for n := r.U32(); n > 0 && r.err == nil; n-- {
+			r.U32()
+		}
+		r.U32()
+	case c == 0x11 || c == 0x13: // call_indirect, return_call_indirect
+		in.Index = r.U32()
+		r.U32()
+	case c == 0x1c: // select t*
+		r.Bytes(int(r.U32()))
+	case c >= 0x28 && c <= 0x3e: // loads and stores
+		r.U32()
+		r.U32()
+	case c == 0x3f || c == 0x40: // memory.size, memory.grow
+		r.Byte()
+	case c == 0x41:
+		r.S32()
+	case c == 0x42:
+		r.S64()
+	case c == 0x43:
+		r.Bytes(4)
+	case c == 0x44:
+		r.Bytes(8)
+	case c == 0xd0: // ref.null
+		r.Byte()
+	case c == 0xfc:
+		sub := r.U32()
+		in.Op = Op(0xfc00 | sub&0xff)
+		switch sub {
+		case 8: // memory.init
+			in.Index = r.U32()
+			r.Byte()
+		case 9, 13, 15, 16, 17: // data.drop, elem.drop, table.grow, size, fill
+			in.Index = r.U32()
+		case 10, 12, 14: // memory.copy, table.init, table.copy
+			r.U32()
+			r.U32()
+		case 11: // memory.fill
+			r.Byte()
+		default:
+			if sub > 17 {
+				r.fail(fmt.Errorf("unknown instruction 0xfc %d", sub))
+			}
+		}
+	case c == 0x05 || c == 0x0b || c <= 0x01 || c == 0x0f || c == 0x1a || c == 0x1b || c >= 0x45 && c <= 0xc4 || c == 0xd1:
+		// no immediates
+	default:
+		r.fail(fmt.Errorf("unknown instruction 0x%02x", c))
+	}
+	in.End = r.off
+	return in
+}
+
+func (r *Reader) blockType() {
+	c := r.Byte()
+	if c == BlockVoid || c >= 0x6f && c <= 0x7f {
+		return
+	}
+	// a type index as signed LEB128
+	r.off--
+	r.S64()
+}
+
+// constExpr reads a constant expression, returning it with its end.
+func (r *Reader) constExpr() []byte {
+	start := r.off
+	for r.err == nil {
+		if in := r.Instruction(); in.Op == OpEnd {
+			break
+		}
+	}
+	return r.b[start:r.off]
+}
+
+// Func is a function body of the code section.
+type Func struct {
+	// Locals are the encoded local declarations, their count included.
+	Locals []byte
+	// Code are the instructions, up to and including the final end.
+	Code []byte
+}
+
+// Instructions decodes the instructions of f's code, with offsets relative
+// to the start of the code.
+func (f *Func) Instructions() ([]Instruction, error) {
+	var ins []Instruction
+	r := NewReader(f.Code)
+	for r.Len() > 0 {
+		ins = append(ins, r.Instruction())
+	}
+	return ins, r.Err()
+}
+
+// Code decodes the code section, the bodies of the functions the module
+// defines, which come after the imported ones in the function index space.
+func (m *Module) Code() ([]Func, error) {
+	s := m.Section(CodeSection)
+	if s == nil {
+		return nil, nil
+	}
+	r := NewReader(s.Data)
+	funcs := make([]Func, r.U32())
+	for i := range funcs {
+		body := NewReader(r.Bytes(int(r.U32())))
+		for n := body.U32(); n > 0 && body.err == nil; n-- {
+			body.U32()
+			body.Byte()
+		}
+		funcs[i].Locals = body.b[:body.off]
+		funcs[i].Code = body.b[body.off:]
+		if err := body.Err(); err != nil {
+			return nil, fmt.Errorf("function %d: %v", i, err)
+		}
+	}
+	return funcs, r.Err()
+}
+
+// SetCode replaces the code section.
+func (m *Module) SetCode(funcs []Func) {
+	data := AppendU32(nil, uint32(len(funcs)))
+	for _, f := range funcs {
+		data = AppendU32(data, uint32(len(f.Locals)+len(f.Code)))
+		data = append(data, f.Locals...)
+		data = append(data, f.Code...)
+	}
+	m.SetSection(&Section{ID: CodeSection, Data: data})
+}
@@ -0,0 +1,10 @@
+// Package wasm reads and writes WebAssembly binaries, as far as the tools
+// in this module need to look into and rewrite what the Go compiler emits.
+//
+// Parse splits a module into its sections, keeping their payloads as they
+// are; Bytes puts them back together, so a module comes out as it went in
+// unless a section was replaced. Only the section sizes may be shorter:
+// Bytes encodes them minimally, while the Go linker pads them to five
+// bytes. The accessors decode the
+// sections they are named after, and Instructions walks function bodies.
+package wasm
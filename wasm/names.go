@@ -0,0 +1,77 @@
+package wasm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FuncNames decodes the function names of the name section, by function
+// index. Modules without one give an empty map.
+func (m *Module) FuncNames() (map[uint32]string, error) {
+	names := make(map[uint32]string)
+	s := m.Custom("name")
+	if s == nil {
+		return names, nil
+	}
+	r := NewReader(s.Data)
+	for r.Len() > 0 {
+		id := r.Byte()
+		sub := NewReader(r.Bytes(int(r.U32())))
+		if id != 1 {
+			continue
+		}
+		for n := sub.U32(); n > 0 && sub.err == nil; n-- {
+			i := sub.U32()
+			names[i] = sub.Name()
+		}
+		if err := sub.Err(); err != nil {
+			return nil, fmt.Errorf("function names: %v", err)
+		}
+	}
+	return names, r.Err()
+}
+
+// domain matches the start of names of packages whose path starts with a
+// domain, like golang.org_x_net_html.Parse, optionally followed by a major
+// version element, like gopkg.in_yaml.v3.Unmarshal.
+var domain = regexp.MustCompile(`^[\w-]+\.(?:com|org|net|io|dev|in|me|co|sh|app|cloud|info|edu|gov)_\w*(?:\.v\d+)?`)
+
+// GoPackage returns the package of a function named by the Go linker, or ""
+// for functions outside of packages, like the wasm_pc_f_loop trampoline.
+//
+// The linker replaces what isn't a letter, digit, underscore or dot in
+// names by underscores, so the package comes back that way too:
+// go_to_js_events for go-to-js/events, from go_to_js_events.__Tracker_.Set
+// for (*Tracker).Set. Its end is the first dot, unless the path starts
+// with a domain, whose dots are kept.
+func GoPackage(name string) string {
+	if p := domain.FindString(name); p != "" && strings.HasPrefix(name[len(p):], ".") {
+		return p
+	}
+	i := strings.IndexByte(name, '.')
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// GoVersion returns the version of Go that built the module, like
+// go1.21.5, from its producers section, or "" if it doesn't say.
+func (m *Module) GoVersion() (string, error) {
+	s := m.Custom("producers")
+	if s == nil {
+		return "", nil
+	}
+	r := NewReader(s.Data)
+	for fields := r.U32(); fields > 0 && r.err == nil; fields-- {
+		field := r.Name()
+		for values := r.U32(); values > 0 && r.err == nil; values-- {
+			name, version := r.Name(), r.Name()
+			if field == "language" && name == "Go" {
+				return version, r.Err()
+			}
+		}
+	}
+	return "", r.Err()
+}
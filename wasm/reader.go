@@ -0,0 +1,163 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var errEOF = errors.New("unexpected end of data")
+
+// Reader decodes the values of the binary format from a byte slice. The
+// first error sticks: later reads return zero values and Err reports it.
+type Reader struct {
+	b   []byte
+	off int
+	err error
+}
+
+// NewReader returns a Reader reading b from its start.
+func NewReader(b []byte) *Reader {
+	return &Reader{b: b}
+}
+
+// Err is the first error a read ran into.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Offset is the position of the next read in the data.
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// Len is the number of bytes left.
+func (r *Reader) Len() int {
+	return len(r.b) - r.off
+}
+
+func (r *Reader) fail(err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("offset %d: %w", r.off, err)
+	}
+	r.off = len(r.b)
+}
+
+// Byte reads a byte.
+func (r *Reader) Byte() byte {
+	if r.off >= len(r.b) {
+		r.fail(errEOF)
+		return 0
+	}
+	c := r.b[r.off]
+	r.off++
+	return c
+}
+
+// Bytes reads n bytes, sharing memory with the data.
+func (r *Reader) Bytes(n int) []byte {
+	if n < 0 || n > r.Len() {
+		r.fail(errEOF)
+		return nil
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+// U32 reads an unsigned LEB128 number of up to 32 bits.
+func (r *Reader) U32() uint32 {
+	v := r.leb(32, false)
+	return uint32(v)
+}
+
+// U64 reads an unsigned LEB128 number of up to 64 bits.
+func (r *Reader) U64() uint64 {
+	return r.leb(64, false)
+}
+
+// S32 reads a signed LEB128 number of up to 32 bits.
+func (r *Reader) S32() int32 {
+	return int32(r.leb(32, true))
+}
+
+// S64 reads a signed LEB128 number of up to 64 bits.
+func (r *Reader) S64() int64 {
+	return int64(r.leb(64, true))
+}
+
+func (r *Reader) leb(bits uint, signed bool) uint64 {
+	var v uint64
+	var shift uint
+	for {
+		c := r.Byte()
+		if r.err != nil {
+			return 0
+		}
+		if shift >= bits {
+			r.fail(errors.New("LEB128 number too long"))
+			return 0
+		}
+		v |= uint64(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			if signed && shift < 64 && c&0x40 != 0 {
+				v |= ^uint64(0) << shift
+			}
+			return v
+		}
+	}
+}
+
+// Name reads a length prefixed UTF-8 string.
+func (r *Reader) Name() string {
+	b := r.Bytes(int(r.U32()))
+	if !utf8.Valid(b) {
+		r.fail(errors.New("name is not UTF-8"))
+		return ""
+	}
+	return string(b)
+}
+
+// limits skips the limits of a table or memory.
+func (r *Reader) limits() {
+	if r.Byte()&1 != 0 {
+		r.U32()
+	}
+	r.U32()
+}
+
+// AppendU32 appends v as unsigned LEB128.
+func AppendU32(b []byte, v uint32) []byte {
+	return AppendU64(b, uint64(v))
+}
+
+// AppendU64 appends v as unsigned LEB128.
+func AppendU64(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// AppendS64 appends v as signed LEB128.
+func AppendS64(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 && c&0x40 == 0 || v == -1 && c&0x40 != 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// AppendName appends s with its length.
+func AppendName(b []byte, s string) []byte {
+	b = AppendU32(b, uint32(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,311 @@
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Section ids.
+const (
+	CustomSection    = 0
+	TypeSection      = 1
+	ImportSection    = 2
+	FunctionSection  = 3
+	TableSection     = 4
+	MemorySection    = 5
+	GlobalSection    = 6
+	ExportSection    = 7
+	StartSection     = 8
+	ElementSection   = 9
+	CodeSection      = 10
+	DataSection      = 11
+	DataCountSection = 12
+)
+
+// External kinds of imports and exports.
+const (
+	FuncKind   = 0
+	TableKind  = 1
+	MemoryKind = 2
+	GlobalKind = 3
+)
+
+// Value types.
+const (
+	I32 = 0x7f
+	I64 = 0x7e
+	F32 = 0x7d
+	F64 = 0x7c
+)
+
+var magic = []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+
+// Module is a WebAssembly module as its sections, in order.
+type Module struct {
+	Sections []*Section
+}
+
+// Section is a section of a module. Data is its payload, for custom
+// sections the part after the name.
+type Section struct {
+	ID   byte
+	Name string
+	Data []byte
+}
+
+// Parse splits the module b into its sections. The sections' data shares
+// memory with b.
+func Parse(b []byte) (*Module, error) {
+	if !bytes.HasPrefix(b, magic) {
+		return nil, errors.New("not a version 1 wasm module")
+	}
+	m := new(Module)
+	r := &Reader{b: b, off: len(magic)}
+	for r.Len() > 0 {
+		start := r.off
+		s := &Section{ID: r.Byte()}
+		data := r.Bytes(int(r.U32()))
+		if r.err != nil {
+			return nil, fmt.Errorf("section at offset %d: %v", start, r.err)
+		}
+		if s.ID == CustomSection {
+			cr := NewReader(data)
+			s.Name = cr.Name()
+			if cr.err != nil {
+				return nil, fmt.Errorf("custom section at offset %d: %v", start, cr.err)
+			}
+			data = data[cr.off:]
+		}
+		s.Data = data
+		m.Sections = append(m.Sections, s)
+	}
+	return m, nil
+}
+
+// Bytes encodes the module.
+func (m *Module) Bytes() []byte {
+	out := append([]byte(nil), magic...)
+	for _, s := range m.Sections {
+		data := s.Data
+		if s.ID == CustomSection {
+			data = append(AppendName(nil, s.Name), data...)
+		}
+		out = append(out, s.ID)
+		out = AppendU32(out, uint32(len(data)))
+		out = append(out, data...)
+	}
+	return out
+}
+
+// Section returns the first section with id, or nil. Custom sections are
+// found by name with Custom.
+func (m *Module) Section(id byte) *Section {
+	for _, s := range m.Sections {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// Custom returns the custom section called name, or nil.
+func (m *Module) Custom(name string) *Section {
+	for _, s := range m.Sections {
+		if s.ID == CustomSection && s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// SetSection replaces the section with s's id by s, or inserts s where
+// the order of known sections puts it.
+func (m *Module) SetSection(s *Section) {
+	for i, old := range m.Sections {
+		if old.ID == s.ID {
+			m.Sections[i] = s
+			return
+		}
+	}
+	i := 0
+	for i < len(m.Sections) && (m.Sections[i].ID == CustomSection || order(m.Sections[i].ID) < order(s.ID)) {
+		i++
+	}
+	m.Sections = append(m.Sections[:i], append([]*Section{s}, m.Sections[i:]...)...)
+}
+
+// order is the position of a known section in a module; data count comes
+// before code although its id is larger.
+func order(id byte) int {
+	if id == DataCountSection {
+		return CodeSection*2 - 1
+	}
+	return int(id) * 2
+}
+
+// Import is an entry of the import section. Desc is the encoded type of
+// the import: a type index for functions, limits and types otherwise.
+type Import struct {
+	Module, Name string
+	Kind         byte
+	Desc         []byte
+}
+
+// TypeIndex is the type of a function import.
+func (imp *Import) TypeIndex() uint32 {
+	return NewReader(imp.Desc).U32()
+}
+
+// Imports decodes the import section.
+func (m *Module) Imports() ([]Import, error) {
+	s := m.Section(ImportSection)
+	if s == nil {
+		return nil, nil
+	}
+	r := NewReader(s.Data)
+	imports := make([]Import, r.U32())
+	for i := range imports {
+		imp := &imports[i]
+		imp.Module = r.Name()
+		imp.Name = r.Name()
+		imp.Kind = r.Byte()
+		start := r.off
+		switch imp.Kind {
+		case FuncKind:
+			r.U32()
+		case TableKind:
+			r.Byte()
+			r.limits()
+		case MemoryKind:
+			r.limits()
+		case GlobalKind:
+			r.Byte()
+			r.Byte()
+		default:
+			return nil, fmt.Errorf("import %s.%s: unknown kind %d", imp.Module, imp.Name, imp.Kind)
+		}
+		imp.Desc = r.b[start:r.off]
+	}
+	return imports, r.Err()
+}
+
+// ImportCount is the number of imports of kind. Imports come first in the
+// index space of their kind.
+func (m *Module) ImportCount(kind byte) (int, error) {
+	imports, err := m.Imports()
+	n := 0
+	for _, imp := range imports {
+		if imp.Kind == kind {
+			n++
+		}
+	}
+	return n, err
+}
+
+// Export is an entry of the export section.
+type Export struct {
+	Name  string
+	Kind  byte
+	Index uint32
+}
+
+// Exports decodes the export section.
+func (m *Module) Exports() ([]Export, error) {
+	s := m.Section(ExportSection)
+	if s == nil {
+		return nil, nil
+	}
+	r := NewReader(s.Data)
+	exports := make([]Export, r.U32())
+	for i := range exports {
+		exports[i] = Export{Name: r.Name(), Kind: r.Byte(), Index: r.U32()}
+	}
+	return exports, r.Err()
+}
+
+// SetExports replaces the export section.
+func (m *Module) SetExports(exports []Export) {
+	data := AppendU32(nil, uint32(len(exports)))
+	for _, e := range exports {
+		data = AppendName(data, e.Name)
+		data = append(data, e.Kind)
+		data = AppendU32(data, e.Index)
+	}
+	m.SetSection(&Section{ID: ExportSection, Data: data})
+}
+
+// Global is an entry of the global section. Init is its constant
+// initializer expression including the final end.
+type Global struct {
+	Type    byte
+	Mutable bool
+	Init    []byte
+}
+
+// Globals decodes the global section.
+func (m *Module) Globals() ([]Global, error) {
+	s := m.Section(GlobalSection)
+	if s == nil {
+		return nil, nil
+	}
+	r := NewReader(s.Data)
+	globals := make([]Global, r.U32())
+	for i := range globals {
+		g := &globals[i]
+		g.Type = r.Byte()
+		g.Mutable = r.Byte() == 1
+		g.Init = r.constExpr()
+	}
+	return globals, r.Err()
+}
+
+// SetGlobals replaces the global section.
+func (m *Module) SetGlobals(globals []Global) {
+	data := AppendU32(nil, uint32(len(globals)))
+	for _, g := range globals {
+		mut := byte(0)
+		if g.Mutable {
+			mut = 1
+		}
+		data = append(data, g.Type, mut)
+		data = append(data, g.Init...)
+	}
+	m.SetSection(&Section{ID: GlobalSection, Data: data})
+}
+
+// Data is a segment of the data section. Active segments are copied into
+// Memory at Offset, a constant expression including the final end, when
+// the module is instantiated; passive ones by memory.init.
+type Data struct {
+	Passive bool
+	Memory  uint32
+	Offset  []byte
+	Init    []byte
+}
+
+// Data decodes the data section.
+func (m *Module) Data() ([]Data, error) {
+	s := m.Section(DataSection)
+	if s == nil {
+		return nil, nil
+	}
+	r := NewReader(s.Data)
+	segments := make([]Data, r.U32())
+	for i := range segments {
+		d := &segments[i]
+		switch flags := r.U32(); flags {
+		case 0, 2:
+			if flags == 2 {
+				d.Memory = r.U32()
+			}
+			d.Offset = r.constExpr()
+		case 1:
+			d.Passive = true
+		default:
+			return nil, fmt.Errorf("data segment %d: unknown flags %d", i, flags)
+		}
+		d.Init = r.Bytes(int(r.U32()))
+	}
+	return segments, r.Err()
+}
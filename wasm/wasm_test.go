@@ -0,0 +1,392 @@
+package wasm
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// section encodes a section with id and data.
+func section(id byte, data ...byte) []byte {
+	return append(AppendU32([]byte{id}, uint32(len(data))), data...)
+}
+
+// testModule is a module importing a function and a global, defining a
+// function with a loop and exporting it.
+func testModule() []byte {
+	b := append([]byte(nil), magic...)
+	b = append(b, section(TypeSection, 1, 0x60, 0, 0)...)
+	b = append(b, section(ImportSection,
+		2,
+		3, 'e', 'n', 'v', 1, 'f', FuncKind, 0,
+		3, 'e', 'n', 'v', 1, 'g', GlobalKind, I32, 0,
+	)...)
+	b = append(b, section(FunctionSection, 1, 0)...)
+	b = append(b, section(GlobalSection, 1, I64, 1, byte(OpI64Const), 0x7f, byte(OpEnd))...)
+	b = append(b, section(ExportSection, 1, 3, 'r', 'u', 'n', FuncKind, 1)...)
+	code := []byte{
+		1, 1, I32, // one i32 local
+		byte(OpLoop), BlockVoid,
+		byte(OpCall), 0,
+		0x20, 0, // local.get 0
+		byte(OpBrIf), 0,
+		byte(OpEnd),
+		byte(OpEnd),
+	}
+	b = append(b, section(CodeSection, append([]byte{1, byte(len(code))}, code...)...)...)
+	return append(b, section(CustomSection, 4, 'n', 'o', 't', 'e', 'h', 'i')...)
+}
+
+func TestRoundTrip(t *testing.T) {
+	b := testModule()
+	m, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Sections) != 7 {
+		t.Errorf("got %d sections, want 7", len(m.Sections))
+	}
+	if s := m.Custom("note"); s == nil || string(s.Data) != "hi" {
+		t.Errorf("custom section note = %+v, want data hi", s)
+	}
+	if got := m.Bytes(); !bytes.Equal(got, b) {
+		t.Errorf("Bytes() = %x, want %x", got, b)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, b := range map[string][]byte{
+		"empty":     nil,
+		"version 2": {0, 'a', 's', 'm', 2, 0, 0, 0},
+		"truncated": testModule()[:20],
+	} {
+		if _, err := Parse(b); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", name)
+		}
+	}
+}
+
+func TestSections(t *testing.T) {
+	m, err := Parse(testModule())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imports, err := m.Imports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imports) != 2 || imports[0].Name != "f" || imports[0].TypeIndex() != 0 || imports[1].Kind != GlobalKind {
+		t.Errorf("Imports() = %+v", imports)
+	}
+	if n, _ := m.ImportCount(FuncKind); n != 1 {
+		t.Errorf("ImportCount(FuncKind) = %d, want 1", n)
+	}
+
+	exports, err := m.Exports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 1 || exports[0] != (Export{"run", FuncKind, 1}) {
+		t.Errorf("Exports() = %+v", exports)
+	}
+
+	globals, err := m.Globals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(globals) != 1 || globals[0].Type != I64 || !globals[0].Mutable || !bytes.Equal(globals[0].Init, []byte{byte(OpI64Const), 0x7f, byte(OpEnd)}) {
+		t.Errorf("Globals() = %+v", globals)
+	}
+
+	funcs, err := m.Code()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 1 || !bytes.Equal(funcs[0].Locals, []byte{1, 1, I32}) {
+		t.Fatalf("Code() = %+v", funcs)
+	}
+	ins, err := funcs[0].Instructions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []Op
+	for _, in := range ins {
+		ops = append(ops, in.Op)
+	}
+	want := []Op{OpLoop, OpCall, 0x20, OpBrIf, OpEnd, OpEnd}
+	if len(ops) != len(want) {
+		t.Fatalf("instructions %x, want %x", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("instructions %x, want %x", ops, want)
+		}
+	}
+}
+
+func TestFuncNames(t *testing.T) {
+	b := testModule()
+	// a module name subsection, which is skipped, and names for both
+	// functions
+	b = append(b, section(CustomSection,
+		4, 'n', 'a', 'm', 'e',
+		0, 2, 1, 'm',
+		1, 10, 2, 0, 1, 'f', 1, 4, 'm', '.', 'r', 'n',
+	)...)
+	m, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := m.FuncNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "f" || names[1] != "m.rn" {
+		t.Errorf("FuncNames() = %v", names)
+	}
+
+	m, _ = Parse(testModule())
+	if names, err := m.FuncNames(); err != nil || len(names) != 0 {
+		t.Errorf("FuncNames() without a name section = %v, %v", names, err)
+	}
+}
+
+func TestData(t *testing.T) {
+	b := append(testModule(), section(DataSection,
+		3,
+		0, byte(OpI32Const), 8, byte(OpEnd), 2, 'h', 'i',
+		1, 1, '!',
+		2, 0, byte(OpI32Const), 0x80, 0x01, byte(OpEnd), 0,
+	)...)
+	m, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Fatalf("Data() = %+v, want 3 segments", data)
+	}
+	if d := data[0]; d.Passive || !bytes.Equal(d.Offset, []byte{byte(OpI32Const), 8, byte(OpEnd)}) || string(d.Init) != "hi" {
+		t.Errorf("segment 0 = %+v", d)
+	}
+	if d := data[1]; !d.Passive || d.Offset != nil || string(d.Init) != "!" {
+		t.Errorf("segment 1 = %+v", d)
+	}
+	if d := data[2]; d.Passive || len(d.Offset) != 4 || len(d.Init) != 0 {
+		t.Errorf("segment 2 = %+v", d)
+	}
+}
+
+func TestGoPackage(t *testing.T) {
+	for name, want := range map[string]string{
+		"runtime.mallocgc":                              "runtime",
+		"runtime.__mspan_.typePointersOf":               "runtime",
+		"runtime.mapassign_fast64":                      "runtime",
+		"go_to_js_events.__Tracker_.Set":                "go_to_js_events",
+		"internal_strconv.shortFloat_go.shape.float64_": "internal_strconv",
+		"golang.org_x_net_html.Parse":                   "golang.org_x_net_html",
+		"gopkg.in_yaml.v3.Unmarshal":                    "gopkg.in_yaml.v3",
+		"github.com_user_repo.__T_.M.func1":             "github.com_user_repo",
+		"type_.eq.M36K4M8":                              "type_",
+		"wasm_pc_f_loop":                                "",
+		"go_buildid":                                    "",
+	} {
+		if got := GoPackage(name); got != want {
+			t.Errorf("GoPackage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	producers := []byte{9, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', 's', 2}
+	producers = append(producers, 12, 'p', 'r', 'o', 'c', 'e', 's', 's', 'e', 'd', '-', 'b', 'y', 1, 1, 'x', 1, '1')
+	producers = append(producers, 8, 'l', 'a', 'n', 'g', 'u', 'a', 'g', 'e', 1, 2, 'G', 'o', 6, 'g', 'o', '1', '.', '2', '1')
+	m, err := Parse(append(testModule(), section(CustomSection, producers...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := m.GoVersion(); v != "go1.21" || err != nil {
+		t.Errorf("GoVersion() = %q, %v, want go1.21", v, err)
+	}
+	m, _ = Parse(testModule())
+	if v, err := m.GoVersion(); v != "" || err != nil {
+		t.Errorf("GoVersion() without producers = %q, %v", v, err)
+	}
+}
+
+func TestSetSection(t *testing.T) {
+	m, err := Parse(testModule())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetExports(append([]Export(nil), Export{"x", GlobalKind, 1}))
+	m.SetSection(&Section{ID: DataCountSection, Data: []byte{0}})
+	m.SetSection(&Section{ID: StartSection, Data: []byte{1}})
+
+	var ids []byte
+	for _, s := range m.Sections {
+		ids = append(ids, s.ID)
+	}
+	want := []byte{TypeSection, ImportSection, FunctionSection, GlobalSection, ExportSection, StartSection, DataCountSection, CodeSection, CustomSection}
+	if !bytes.Equal(ids, want) {
+		t.Errorf("section ids %v, want %v", ids, want)
+	}
+	if exports, _ := m.Exports(); len(exports) != 1 || exports[0].Name != "x" {
+		t.Errorf("Exports() after SetExports = %+v", exports)
+	}
+	if _, err := Parse(m.Bytes()); err != nil {
+		t.Errorf("parsing the result: %v", err)
+	}
+}
+
+func TestLEB(t *testing.T) {
+	for _, v := range []uint64{0, 1, 127, 128, 624485, math.MaxUint32, math.MaxUint64} {
+		r := NewReader(AppendU64(nil, v))
+		if got := r.U64(); got != v || r.Err() != nil || r.Len() != 0 {
+			t.Errorf("U64 of AppendU64(%d) = %d, %v", v, got, r.Err())
+		}
+	}
+	for _, v := range []int64{0, 1, -1, 63, 64, -64, -65, -123456, math.MaxInt64, math.MinInt64} {
+		r := NewReader(AppendS64(nil, v))
+		if got := r.S64(); got != v || r.Err() != nil || r.Len() != 0 {
+			t.Errorf("S64 of AppendS64(%d) = %d, %v", v, got, r.Err())
+		}
+	}
+	// the example of the spec
+	if got := AppendU32(nil, 624485); !bytes.Equal(got, []byte{0xe5, 0x8e, 0x26}) {
+		t.Errorf("AppendU32(624485) = %x", got)
+	}
+
+	r := NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01})
+	if r.U32(); r.Err() == nil {
+		t.Error("U32 of 6 bytes succeeded, want an error")
+	}
+	r = NewReader([]byte{0x80})
+	if r.U32(); r.Err() == nil {
+		t.Error("U32 of a truncated number succeeded, want an error")
+	}
+}
+
+func TestInstruction(t *testing.T) {
+	for _, tc := range []struct {
+		code  []byte
+		op    Op
+		index uint32
+	}{
+		{[]byte{byte(OpCall), 0xe5, 0x8e, 0x26}, OpCall, 624485},
+		{[]byte{byte(OpBrTable), 2, 0, 1, 0}, OpBrTable, 0},
+		{[]byte{byte(OpCallIndirect), 3, 0}, OpCallIndirect, 3},
+		{[]byte{0x28, 2, 8}, 0x28, 0},                         // i32.load
+		{[]byte{byte(OpI64Const), 0x80, 0x7f}, OpI64Const, 0}, // -128
+		{[]byte{0x44, 0, 0, 0, 0, 0, 0, 0, 0}, 0x44, 0},       // f64.const
+		{[]byte{0xfc, 10, 0, 0}, 0xfc0a, 0},                   // memory.copy
+		{[]byte{0xfc, 8, 1, 0}, 0xfc08, 1},                    // memory.init
+		{[]byte{byte(OpBlock), 0x7e}, OpBlock, 0},             // block (result i64)
+		{[]byte{byte(OpIf), 0x05}, OpIf, 0},                   // if with type index
+		{[]byte{0xc4}, 0xc4, 0},                               // i64.extend32_s
+		{[]byte{byte(OpGlobalSet), 0x80, 0x01}, OpGlobalSet, 128},
+	} {
+		r := NewReader(tc.code)
+		in := r.Instruction()
+		if r.Err() != nil || in.Op != tc.op || in.Index != tc.index || in.Offset != 0 || in.End != len(tc.code) {
+			t.Errorf("Instruction of %x = %+v, %v", tc.code, in, r.Err())
+		}
+	}
+
+	for _, code := range [][]byte{{0xfd, 0}, {0x27}, {0xfc, 18}, {byte(OpCall)}} {
+		r := NewReader(code)
+		if r.Instruction(); r.Err() == nil {
+			t.Errorf("Instruction of %x succeeded, want an error", code)
+		}
+	}
+}
+
+// TestGoModule decodes every function of the module's program built for
+// js/wasm and expects its sections to come out of Bytes unchanged.
+func TestGoModule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the program")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	out := filepath.Join(t.TempDir(), "main.wasm")
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = ".."
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building: %v\n%s", err, b)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(m.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Sections) != len(m.Sections) {
+		t.Fatalf("got %d sections back, want %d", len(again.Sections), len(m.Sections))
+	}
+	for i, s := range m.Sections {
+		if got := again.Sections[i]; got.ID != s.ID || got.Name != s.Name || !bytes.Equal(got.Data, s.Data) {
+			t.Errorf("section %d (id %d %s) differs after Bytes", i, s.ID, s.Name)
+		}
+	}
+	funcs, err := m.Code()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) < 1000 {
+		t.Errorf("got %d functions, want a Go program's worth", len(funcs))
+	}
+	for i, f := range funcs {
+		ins, err := f.Instructions()
+		if err != nil {
+			t.Fatalf("function %d: %v", i, err)
+		}
+		if len(ins) == 0 || ins[len(ins)-1].Op != OpEnd {
+			t.Fatalf("function %d doesn't end with end", i)
+		}
+	}
+	if _, err := m.Imports(); err != nil {
+		t.Error(err)
+	}
+	if _, err := m.Data(); err != nil {
+		t.Error(err)
+	}
+	names, err := m.FuncNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, _ := m.ImportCount(FuncKind)
+	if len(names) != len(funcs) || names[uint32(imported)] == "" {
+		t.Errorf("got %d function names for %d functions", len(names), len(funcs))
+	}
+	var main bool
+	for _, name := range names {
+		main = main || name == "main.main"
+	}
+	if !main {
+		t.Error("no function named main.main")
+	}
+	if v, err := m.GoVersion(); !strings.HasPrefix(v, "go1.") || err != nil {
+		t.Errorf("GoVersion() = %q, %v", v, err)
+	}
+	if _, err := m.Globals(); err != nil {
+		t.Error(err)
+	}
+}